@@ -0,0 +1,138 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// isArchive reports whether path names a .tar.gz or .zip bundle of
+// manifests, which is validated member by member.
+func isArchive(path string) bool {
+	for _, suffix := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// maxMemberSize is the size of the largest archive member that is
+// validated. Larger members are reported without being read to the end, so
+// that an archive cannot exhaust memory.
+const maxMemberSize = 16 << 20
+
+// errStopped ends the reading of an archive once checkJobs takes no more
+// members.
+var errStopped = errors.New("stopped")
+
+// checkArchive validates the manifests in the archive at path as they are
+// read from it, using checkJobs. The members are named path:member, and the
+// results are in archive order. Members matching one of the ignore globs
+// are returned separately in skipped. stopped reports whether failFast or
+// ctx ended the reading before the end of the archive.
+func checkArchive(ctx context.Context, path string, ignore []string, jobs int, failFast bool, newChecker func() *checker) (results []*fileResult, skipped []string, stopped bool, err error) {
+	results, err = checkJobs(ctx, jobs, failFast, newChecker, func(send func(job) bool) error {
+		add := func(name string, r io.Reader) error {
+			if !isManifest(name) {
+				return nil
+			}
+			name = strings.TrimPrefix(name, "./")
+			key := path + ":" + name
+			if ignored(name, ignore) {
+				skipped = append(skipped, key)
+				return nil
+			}
+			content, err := io.ReadAll(io.LimitReader(r, maxMemberSize+1))
+			if err != nil {
+				return err
+			}
+			j := job{name: key, member: true, content: content}
+			if len(content) > maxMemberSize {
+				j.content, j.err = nil, fmt.Errorf("member exceeds %d MiB", maxMemberSize>>20)
+			}
+			if !send(j) {
+				return errStopped
+			}
+			return nil
+		}
+
+		if strings.HasSuffix(path, ".zip") {
+			return readZip(path, add)
+		}
+		return readTarGz(path, add)
+	})
+	if errors.Is(err, errStopped) {
+		return results, skipped, true, nil
+	}
+	return results, skipped, false, err
+}
+
+func readZip(path string, add func(string, io.Reader) error) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = add(f.Name, rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readTarGz(path string, add func(string, io.Reader) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := add(header.Name, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// isManifest reports whether name has the extension of a YAML or JSON
+// file. JSON is a subset of YAML, so both are validated the same way.
+func isManifest(name string) bool {
+	switch filepath.Ext(name) {
+	case ".yaml", ".yml", ".json":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTarGz writes an archive of the given members, in order, to a
+// temporary file and returns its name.
+func writeTarGz(t *testing.T, members [][2]string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, m := range members {
+		if err := tw.WriteHeader(&tar.Header{Name: m[0], Mode: 0o644, Size: int64(len(m[1]))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(m[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestCheckArchive(t *testing.T) {
+	large := "# " + strings.Repeat("x", maxMemberSize) + "\n" + benchmarkManifest
+	path := writeTarGz(t, [][2]string{
+		{"./valid.yaml", benchmarkManifest},
+		{"large.yaml", large},
+		{"README.md", "not a manifest"},
+		{"ignored/pod.yaml", "not: [valid"},
+		{"invalid.yaml", "kind: Pod\n"},
+		{"last.yaml", benchmarkManifest},
+	})
+
+	results, skipped, stopped, err := checkArchive(context.Background(), path, []string{"ignored"}, 2, false, func() *checker { return &checker{} })
+	if err != nil || stopped {
+		t.Fatalf("checkArchive() = %v, stopped %v", err, stopped)
+	}
+	if len(skipped) != 1 || skipped[0] != path+":ignored/pod.yaml" {
+		t.Errorf("skipped = %q, want the ignored member", skipped)
+	}
+	want := []struct {
+		name   string
+		valid  bool
+		broken bool
+	}{
+		{path + ":valid.yaml", true, false},
+		{path + ":large.yaml", false, true},
+		{path + ":invalid.yaml", false, false},
+		{path + ":last.yaml", true, false},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("checkArchive() returned %d results, want %d", len(results), len(want))
+	}
+	for i, w := range want {
+		r := results[i]
+		if r.filename != w.name || r.valid() != w.valid || r.broken != w.broken {
+			t.Errorf("result %d = %s valid %v broken %v, want %s valid %v broken %v", i, r.filename, r.valid(), r.broken, w.name, w.valid, w.broken)
+		}
+	}
+
+	// With failFast, the members after the first invalid one are not read.
+	results, _, stopped, err = checkArchive(context.Background(), path, []string{"ignored"}, 1, true, func() *checker { return &checker{} })
+	if err != nil || !stopped || len(results) != 2 {
+		t.Errorf("checkArchive() with failFast = %d results, stopped %v, %v; want it stopped after large.yaml", len(results), stopped, err)
+	}
+}
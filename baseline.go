@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"yamlvalid/validator"
+)
+
+// baselineEntry identifies a known error by the file, line and message it
+// was reported with.
+type baselineEntry struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// baseline is a set of known errors that are not reported again, so that a
+// repository with existing violations only fails on new ones.
+type baseline map[baselineEntry]bool
+
+func newBaselineEntry(e *validator.ValidationError) baselineEntry {
+	return baselineEntry{File: e.Filename, Line: e.Line, Message: e.Message}
+}
+
+// loadBaseline reads a baseline written by writeBaseline.
+func loadBaseline(filename string) (baseline, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var entries []baselineEntry
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, err
+	}
+	b := make(baseline, len(entries))
+	for _, entry := range entries {
+		b[entry] = true
+	}
+	return b, nil
+}
+
+// filter returns the errors of errs that are not in the baseline. Syntax
+// errors are always kept: a file that cannot be parsed was not validated,
+// so it cannot be known to hold only the baselined errors.
+func (b baseline) filter(errs []*validator.ValidationError) []*validator.ValidationError {
+	if b == nil {
+		return errs
+	}
+	var kept []*validator.ValidationError
+	for _, e := range errs {
+		if e.Rule == validator.RuleSyntax || !b[newBaselineEntry(e)] {
+			kept = append(kept, e)
+		}
+	}
+	return kept
+}
+
+// writeBaseline writes every validation error of results to filename as a
+// baseline and returns the number of entries.
+func writeBaseline(filename string, results []*fileResult) (int, error) {
+	entries := make([]baselineEntry, 0)
+	for _, r := range results {
+		for _, e := range r.errs {
+			entries = append(entries, newBaselineEntry(e))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].File != entries[j].File {
+			return entries[i].File < entries[j].File
+		}
+		return entries[i].Line < entries[j].Line
+	})
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return len(entries), os.WriteFile(filename, append(content, '\n'), 0o644)
+}
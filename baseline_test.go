@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBaselineKeepsSyntaxErrors(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "pod.yaml")
+	if err := os.WriteFile(manifest, []byte("apiVersion: v1\nkind: [Pod\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	baselineFile := filepath.Join(dir, "baseline.json")
+
+	if got := runValidate([]string{"-quiet", "-baseline", baselineFile, "-write-baseline", manifest}); got != exitValid {
+		t.Fatalf("writing the baseline exited with %d", got)
+	}
+	if got := runValidate([]string{"-quiet", "-baseline", baselineFile, manifest}); got != exitError {
+		t.Errorf("runValidate() = %d for an unparseable file in the baseline, want %d", got, exitError)
+	}
+}
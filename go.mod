@@ -1,5 +1,5 @@
 module yamlvalid
 
-go 1.18
+go 1.21
 
-require gopkg.in/yaml.v3 v3.0.1 // indirect
+require gopkg.in/yaml.v3 v3.0.1
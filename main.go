@@ -1,461 +1,873 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"regexp"
-	"strconv"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
+	"text/tabwriter"
 
-	"gopkg.in/yaml.v3"
+	"yamlvalid/validator"
 )
 
+// Exit codes. Failures to read or parse a file take precedence over
+// validation errors, so that CI can tell a broken pipeline from an invalid
+// manifest.
 const (
-	domainRequired = "registry.bigbrother.io"
+	exitValid   = 0
+	exitInvalid = 1
+	exitError   = 2
 )
 
-var (
-	validOSNames      = map[string]bool{"linux": true, "windows": true}
-	validProtocols    = map[string]bool{"TCP": true, "UDP": true}
-	validResourceKeys = map[string]bool{"cpu": true, "memory": true}
-	memoryUnitRegex   = regexp.MustCompile(`^(\d+)(Gi|Mi|Ki)$`)
-	snakeCaseRegex    = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
-)
-
-type ValidationError struct {
-	Filename string
-	Line     int
-	Message  string
-}
-
-func (e *ValidationError) Error() string {
-	if e.Line > 0 {
-		return fmt.Sprintf("%s:%d %s", e.Filename, e.Line, e.Message)
+// main dispatches to a subcommand. Without one, the arguments are those of
+// validate, which was the only command before subcommands existed.
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "validate":
+			os.Exit(runValidate(args[1:]))
+		case "schema":
+			os.Exit(runSchema(args[1:]))
+		}
 	}
-	return fmt.Sprintf("%s %s", e.Filename, e.Message)
+	os.Exit(runValidate(args))
 }
 
-func main() {
-	if len(os.Args) != 2 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <yaml-file>\n", os.Args[0])
-		os.Exit(1)
+// runValidate implements the validate command and returns the exit status.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	all := flags.Bool("all", false, "report all validation errors instead of only the first one")
+	output := flags.String("output", "text", "output format: text, table, json or sarif")
+	flags.StringVar(output, "format", "text", "same as -output")
+	strict := flags.Bool("strict", false, "report fields that are not part of the Kubernetes schema")
+	quiet := flags.Bool("quiet", false, "print nothing, only set the exit code")
+	colorMode := flags.String("color", "auto", "colorize text output: auto, always or never")
+	maxErrors := flags.Int("max-errors", 0, "stop reporting after this many errors; 0 means no limit")
+	noLatest := flags.Bool("no-latest", false, "reject images tagged 'latest'")
+	policyFile := flags.String("policy", "", "read allowed registries, forbidden tags, required fields and the name pattern from this YAML file; flags override it")
+	namePattern := flags.String("name-pattern", "", "regular expression container names must match (default snake_case)")
+	requireDigest := flags.Bool("require-digest", false, "require images to be pinned by sha256 digest")
+	requireRequests := flags.Bool("require-requests", false, "require cpu and memory requests on every container")
+	memoryGranularity := flags.String("memory-granularity", "", "require memory requests and limits to be a multiple of this quantity, such as 128Mi")
+	jobs := flags.Int("j", runtime.NumCPU(), "number of files to validate in parallel")
+	keepGoing := flags.Bool("continue", true, "validate every file of a directory or archive; -continue=false stops at the first invalid one")
+	fix := flags.Bool("fix", false, "rewrite memory quantities with wrongly cased units, such as 1gi, before validating")
+	verbose := flags.Bool("v", false, "print a summary line for every valid file")
+	explain := flags.Bool("explain", false, "follow each error with a hint on how to fix it")
+	warnAsError := flags.Bool("warn-as-error", false, "treat warnings as errors")
+	baselineFile := flags.String("baseline", "", "JSON file of known errors that are not reported again")
+	updateBaseline := flags.Bool("write-baseline", false, "write all current errors to the -baseline file instead of reporting them")
+	countOnly := flags.Bool("count-only", false, "print only the totals, such as errors=12 warnings=3 files=40 invalid=5")
+	debug := flags.Bool("debug", false, "log every object the validator descends into to stderr")
+	canonical := flags.Bool("canonical", false, "print valid manifests with sorted keys to stdout, for diffing")
+	var registries, ignore, extendedResources stringList
+	flags.Var(&extendedResources, "extended-resources", "extended resource containers may request as a whole number, such as nvidia.com/gpu; repeatable or comma-separated")
+	flags.Var(&ignore, "ignore", "skip files in a directory whose relative path matches this glob; ** matches any number of directories; repeatable")
+	flags.Var(&registries, "registry", "allowed image registry; repeatable or comma-separated (default registry.bigbrother.io)")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [validate] [flags] <yaml-or-json-file|directory|archive>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s schema\n", os.Args[0])
+		flags.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\nExit status is %d if all files are valid, %d if any file is invalid and %d\nif a file cannot be read or parsed or the command line is wrong. Warnings\nalone do not make a file invalid unless -warn-as-error is set.\n", exitValid, exitInvalid, exitError)
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+	switch *output {
+	case "text", "table", "json", "sarif":
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported output format '%s'\n", *output)
+		flags.Usage()
+		return exitError
+	}
+	if *updateBaseline && *baselineFile == "" {
+		fmt.Fprintln(os.Stderr, "-write-baseline requires -baseline")
+		flags.Usage()
+		return exitError
+	}
+	if *canonical && (*output == "json" || *output == "sarif") {
+		fmt.Fprintf(os.Stderr, "-canonical cannot be combined with -output %s\n", *output)
+		flags.Usage()
+		return exitError
+	}
+	var color bool
+	switch *colorMode {
+	case "auto":
+		color = isTerminal(os.Stderr) && os.Getenv("NO_COLOR") == ""
+	case "always":
+		color = true
+	case "never":
+	default:
+		fmt.Fprintf(os.Stderr, "unsupported color mode '%s'\n", *colorMode)
+		flags.Usage()
+		return exitError
+	}
+
+	for _, pattern := range ignore {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid -ignore pattern '%s': %v\n", pattern, err)
+			flags.Usage()
+			return exitError
+		}
 	}
 
-	filename := os.Args[1]
-	content, err := os.ReadFile(filename)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
-		os.Exit(1)
+	path := flags.Arg(0)
+	files := []string{path}
+	var skipped []string
+	archive := isArchive(path)
+	isDir := archive
+	if archive {
+		if *fix {
+			fmt.Fprintln(os.Stderr, "-fix cannot rewrite the members of an archive")
+			flags.Usage()
+			return exitError
+		}
+	} else if info, err := os.Stat(path); err == nil && info.IsDir() {
+		isDir = true
+		files, skipped, err = findManifests(path, ignore)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return exitError
+		}
 	}
 
-	var root yaml.Node
-	if err := yaml.Unmarshal(content, &root); err != nil {
-		fmt.Fprintf(os.Stderr, "%s: %v\n", filename, err)
-		os.Exit(1)
+	opts := validator.Options{Strict: *strict}
+	if *debug {
+		opts.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}))
 	}
-
-	if len(root.Content) == 0 {
-		exitWithError(filename, 0, "empty YAML document")
+	if *policyFile != "" {
+		p, err := loadPolicy(*policyFile)
+		if err == nil {
+			err = p.apply(&opts)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
 	}
-
-	doc := root.Content[0]
-	if doc.Kind != yaml.MappingNode {
-		exitWithError(filename, doc.Line, "root must be a mapping")
+	// Flags given on the command line override the policy file.
+	var flagErr error
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "registry":
+			opts.Registries = registries
+		case "no-latest":
+			opts.NoLatest = *noLatest
+		case "require-digest":
+			opts.RequireDigest = *requireDigest
+		case "require-requests":
+			opts.RequireRequests = *requireRequests
+		case "extended-resources":
+			opts.ExtendedResources = extendedResources
+		case "memory-granularity":
+			opts.MemoryGranularity = *memoryGranularity
+		case "name-pattern":
+			opts.NamePattern = nil
+			if *namePattern != "" {
+				opts.NamePattern, flagErr = regexp.Compile(*namePattern)
+			}
+		}
+	})
+	if flagErr != nil {
+		fmt.Fprintf(os.Stderr, "invalid -name-pattern: %v\n", flagErr)
+		flags.Usage()
+		return exitError
+	}
+
+	if opts.MemoryGranularity != "" {
+		if granularity, err := validator.ParseMemory(opts.MemoryGranularity); err != nil || granularity == 0 {
+			fmt.Fprintf(os.Stderr, "invalid memory granularity '%s'\n", opts.MemoryGranularity)
+			flags.Usage()
+			return exitError
+		}
 	}
 
-	validator := &podValidator{
-		filename: filename,
-		content:  content,
+	if *jobs < 1 {
+		fmt.Fprintf(os.Stderr, "-j must be at least 1\n")
+		flags.Usage()
+		return exitError
 	}
-	err = validator.validatePod(doc)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
-
-	os.Exit(0)
-}
-
-type podValidator struct {
-	filename string
-	content  []byte
-}
-
-func (v *podValidator) validatePod(node *yaml.Node) error {
-	fields := v.parseMapping(node)
 
-	apiVersion, ok := fields["apiVersion"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "apiVersion is required"}
-	}
-	if apiVersion.Value != "v1" {
-		return &ValidationError{Filename: v.filename, Line: apiVersion.Line, Message: "apiVersion has unsupported value '" + apiVersion.Value + "'"}
+	var known baseline
+	if *baselineFile != "" && !*updateBaseline {
+		var err error
+		if known, err = loadBaseline(*baselineFile); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *baselineFile, err)
+			return exitError
+		}
 	}
 
-	kind, ok := fields["kind"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "kind is required"}
-	}
-	if kind.Value != "Pod" {
-		return &ValidationError{Filename: v.filename, Line: kind.Line, Message: "kind has unsupported value '" + kind.Value + "'"}
+	// A baseline must cover every file, so -write-baseline never stops early.
+	stopEarly := !*keepGoing && !*updateBaseline
+	newChecker := func() *checker {
+		return &checker{all: *all || *updateBaseline || *countOnly, warnAsError: *warnAsError, fix: *fix, canonical: *canonical, baseline: known, opts: opts}
 	}
-
-	metadata, ok := fields["metadata"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "metadata is required"}
+	var results []*fileResult
+	stopped := false
+	if archive {
+		var err error
+		results, skipped, stopped, err = checkArchive(context.Background(), path, ignore, *jobs, stopEarly, newChecker)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			return exitError
+		}
+	} else {
+		results = checkFiles(context.Background(), files, *jobs, stopEarly, newChecker)
 	}
-	if err := v.validateObjectMeta(metadata); err != nil {
-		return err
+	if *updateBaseline {
+		n, err := writeBaseline(*baselineFile, results)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		if !*quiet {
+			fmt.Fprintf(os.Stderr, "wrote %s to %s\n", plural(n, "error"), *baselineFile)
+		}
+		return exitValid
+	}
+	invalid := 0
+	code := exitValid
+	for _, result := range results {
+		if result.broken {
+			code = exitError
+		} else if !result.valid() && code == exitValid {
+			code = exitInvalid
+		}
+		if !result.valid() {
+			invalid++
+		}
 	}
 
-	spec, ok := fields["spec"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "spec is required"}
+	if *quiet {
+		return code
 	}
-	if err := v.validatePodSpec(spec); err != nil {
-		return err
+	if *countOnly {
+		writeCounts(os.Stdout, results, invalid)
+		return code
 	}
 
-	return nil
-}
-
-func (v *podValidator) validateObjectMeta(node *yaml.Node) error {
-	if node.Kind != yaml.MappingNode {
-		return &ValidationError{Filename: v.filename, Line: node.Line, Message: "metadata must be a mapping"}
+	limited := limitErrors(results, *maxErrors)
+	// The hints follow the options, so that a custom name pattern is
+	// explained as such.
+	var hints func(rule string) string
+	if *explain {
+		hints = opts.Explain
 	}
 
-	fields := v.parseMapping(node)
-
-	name, ok := fields["name"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "metadata.name is required"}
-	}
-	if name.Kind != yaml.ScalarNode || name.Value == "" {
-		return &ValidationError{Filename: v.filename, Line: name.Line, Message: "metadata.name must be string"}
-	}
-
-	if ns, ok := fields["namespace"]; ok {
-		if ns.Kind != yaml.ScalarNode || ns.Value == "" {
-			return &ValidationError{Filename: v.filename, Line: ns.Line, Message: "metadata.namespace must be string"}
+	switch *output {
+	case "json":
+		if err := writeJSON(os.Stdout, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
 		}
-	}
-
-	if labels, ok := fields["labels"]; ok {
-		if labels.Kind != yaml.MappingNode {
-			return &ValidationError{Filename: v.filename, Line: labels.Line, Message: "metadata.labels must be a mapping"}
+	case "sarif":
+		if err := writeSARIF(os.Stdout, results, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
 		}
-		for _, child := range labels.Content {
-			if child.Kind != yaml.ScalarNode {
-				return &ValidationError{Filename: v.filename, Line: child.Line, Message: "metadata.labels keys and values must be strings"}
+	case "table":
+		writeTable(os.Stderr, results, hints)
+	default:
+		writeText(os.Stderr, results, color, hints, *verbose)
+		if *verbose {
+			for _, filename := range skipped {
+				fmt.Fprintf(os.Stderr, "%s: skipped\n", filename)
 			}
 		}
 	}
-
-	return nil
-}
-
-func (v *podValidator) validatePodSpec(node *yaml.Node) error {
-	if node.Kind != yaml.MappingNode {
-		return &ValidationError{Filename: v.filename, Line: node.Line, Message: "spec must be a mapping"}
+	if *canonical {
+		writeCanonical(os.Stdout, results)
 	}
-
-	fields := v.parseMapping(node)
-
-	if osNode, ok := fields["os"]; ok {
-		if osNode.Kind != yaml.MappingNode {
-			return &ValidationError{Filename: v.filename, Line: osNode.Line, Message: "spec.os must be a mapping"}
-		}
-		if err := v.validatePodOS(osNode); err != nil {
-			return err
+	if limited {
+		fmt.Fprintln(os.Stderr, "... and more (limit reached)")
+	}
+	if isDir {
+		fmt.Fprintf(os.Stderr, "%d of %d files invalid\n", invalid, len(results))
+		if stopped {
+			fmt.Fprintln(os.Stderr, "stopped at the first invalid file; the rest of the archive was not checked")
+		} else if !archive && len(results) < len(files) {
+			fmt.Fprintf(os.Stderr, "stopped at the first invalid file; %s not checked\n", plural(len(files)-len(results), "file"))
 		}
 	}
+	return code
+}
 
-	containers, ok := fields["containers"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "spec.containers is required"}
+// runSchema implements the schema command, which prints the JSON Schema of
+// the manifests accepted by validate.
+func runSchema(args []string) int {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s schema\n", os.Args[0])
+		flags.PrintDefaults()
 	}
-	if containers.Kind != yaml.SequenceNode {
-		return &ValidationError{Filename: v.filename, Line: containers.Line, Message: "spec.containers must be a sequence"}
+	if err := flags.Parse(args); err != nil {
+		return exitError
 	}
-	if len(containers.Content) == 0 {
-		return &ValidationError{Filename: v.filename, Line: containers.Line, Message: "spec.containers must not be empty"}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return exitError
 	}
 
-	seenNames := make(map[string]bool)
-	for _, container := range containers.Content {
-		if container.Kind != yaml.MappingNode {
-			return &ValidationError{Filename: v.filename, Line: container.Line, Message: "container must be a mapping"}
-		}
-		if err := v.validateContainer(container, seenNames); err != nil {
-			return err
-		}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(validator.Schema()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
 	}
-
-	return nil
+	return exitValid
 }
 
-func (v *podValidator) validatePodOS(node *yaml.Node) error {
-	fields := v.parseMapping(node)
+// stringList is a flag value that collects strings from repeated and
+// comma-separated uses of a flag.
+type stringList []string
 
-	name, ok := fields["name"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "spec.os.name is required"}
-	}
-	if !validOSNames[name.Value] {
-		return &ValidationError{Filename: v.filename, Line: name.Line, Message: "spec.os has unsupported value '" + name.Value + "'"}
-	}
-	return nil
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
 }
 
-func (v *podValidator) validateContainer(node *yaml.Node, seenNames map[string]bool) error {
-	fields := v.parseMapping(node)
-
-	nameNode, ok := fields["name"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "container.name is required"}
-	}
-	if nameNode.Kind != yaml.ScalarNode {
-		return &ValidationError{Filename: v.filename, Line: nameNode.Line, Message: "container.name must be string"}
-	}
-	if !snakeCaseRegex.MatchString(nameNode.Value) {
-		return &ValidationError{Filename: v.filename, Line: nameNode.Line, Message: "container.name has invalid format '" + nameNode.Value + "'"}
-	}
-	if seenNames[nameNode.Value] {
-		return &ValidationError{Filename: v.filename, Line: nameNode.Line, Message: "container.name must be unique within pod"}
-	}
-	seenNames[nameNode.Value] = true
-
-	imageNode, ok := fields["image"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "container.image is required"}
-	}
-	if imageNode.Kind != yaml.ScalarNode || imageNode.Value == "" {
-		return &ValidationError{Filename: v.filename, Line: imageNode.Line, Message: "container.image must be string"}
-	}
-	if err := v.validateImage(imageNode.Value); err != nil {
-		return &ValidationError{Filename: v.filename, Line: imageNode.Line, Message: "container.image " + err.Error()}
+func (l *stringList) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			*l = append(*l, s)
+		}
 	}
+	return nil
+}
 
-	if portsNode, ok := fields["ports"]; ok {
-		if portsNode.Kind != yaml.SequenceNode {
-			return &ValidationError{Filename: v.filename, Line: portsNode.Line, Message: "container.ports must be a sequence"}
+// findManifests returns the YAML and JSON files found under dir, in lexical
+// order. Files matching one of the ignore globs are returned separately in skipped.
+func findManifests(dir string, ignore []string) (files, skipped []string, err error) {
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
-		for _, port := range portsNode.Content {
-			if err := v.validateContainerPort(port); err != nil {
-				return err
-			}
+		if d.IsDir() {
+			return nil
 		}
-	}
-
-	if rp, ok := fields["readinessProbe"]; ok {
-		if rp.Kind != yaml.MappingNode {
-			return &ValidationError{Filename: v.filename, Line: rp.Line, Message: "readinessProbe must be a mapping"}
+		if !isManifest(path) {
+			return nil
 		}
-		if err := v.validateProbe(rp, "readinessProbe"); err != nil {
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
 			return err
 		}
-	}
-
-	if lp, ok := fields["livenessProbe"]; ok {
-		if lp.Kind != yaml.MappingNode {
-			return &ValidationError{Filename: v.filename, Line: lp.Line, Message: "livenessProbe must be a mapping"}
+		if ignored(filepath.ToSlash(rel), ignore) {
+			skipped = append(skipped, path)
+		} else {
+			files = append(files, path)
 		}
-		if err := v.validateProbe(lp, "livenessProbe"); err != nil {
-			return err
+		return nil
+	})
+	return files, skipped, err
+}
+
+// ignored reports whether the slash-separated path rel, or one of the
+// directories containing it, matches one of the globs in ignore.
+func ignored(rel string, ignore []string) bool {
+	elems := strings.Split(rel, "/")
+	for _, pattern := range ignore {
+		patternElems := strings.Split(pattern, "/")
+		for n := len(elems); n > 0; n-- {
+			if matchGlob(patternElems, elems[:n]) {
+				return true
+			}
 		}
 	}
+	return false
+}
 
-	resources, ok := fields["resources"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "container.resources is required"}
+// matchGlob matches path elements against pattern elements, where an element
+// of ** matches any number of path elements and the others are matched with
+// filepath.Match.
+func matchGlob(pattern, elems []string) bool {
+	if len(pattern) == 0 {
+		return len(elems) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(elems); i++ {
+			if matchGlob(pattern[1:], elems[i:]) {
+				return true
+			}
+		}
+		return false
 	}
-	if resources.Kind != yaml.MappingNode {
-		return &ValidationError{Filename: v.filename, Line: resources.Line, Message: "container.resources must be a mapping"}
+	if len(elems) == 0 {
+		return false
 	}
-	if err := v.validateResourceRequirements(resources); err != nil {
-		return err
+	if ok, _ := filepath.Match(pattern[0], elems[0]); !ok {
+		return false
 	}
+	return matchGlob(pattern[1:], elems[1:])
+}
 
-	return nil
+// fileResult holds the outcome of checking a single file: the validation
+// errors to report and, separately, a failure to read it. broken is set when
+// the file could not be read or parsed, even if that error is not reported.
+type fileResult struct {
+	filename string
+	errs     []*validator.ValidationError
+	err      error
+	broken   bool
+	// containers and warnings count what was found in the file, including
+	// warnings that are not reported.
+	containers int
+	warnings   int
+	fixes      []validator.Fix
+	// canonical is the output of validator.Canonical for a valid file when
+	// -canonical is set.
+	canonical []byte
 }
 
-func (v *podValidator) validateImage(image string) error {
-	parts := strings.Split(image, "/")
-	if len(parts) < 2 {
-		return fmt.Errorf("has invalid format '%s'", image)
+// valid reports whether the file was read and has no errors. Warnings do
+// not count.
+func (r *fileResult) valid() bool {
+	if r.err != nil {
+		return false
 	}
-	if parts[0] != domainRequired {
-		return fmt.Errorf("must be in domain '%s'", domainRequired)
+	for _, e := range r.errs {
+		if !e.IsWarning() {
+			return false
+		}
 	}
+	return true
+}
 
-	lastPart := parts[len(parts)-1]
-	if !strings.Contains(lastPart, ":") {
-		return fmt.Errorf("tag is required in '%s'", image)
-	}
-	tag := strings.Split(lastPart, ":")
-	if len(tag) < 2 || tag[1] == "" {
-		return fmt.Errorf("tag is required in '%s'", image)
+// allErrors returns the validation errors followed by the read failure, if
+// any, expressed as an error without a line.
+func (r *fileResult) allErrors() []*validator.ValidationError {
+	errs := make([]*validator.ValidationError, 0, len(r.errs)+1)
+	errs = append(errs, r.errs...)
+	if r.err != nil {
+		errs = append(errs, &validator.ValidationError{Filename: r.filename, Rule: validator.RuleSyntax, Severity: validator.SeverityError, Message: r.err.Error()})
 	}
-
-	return nil
+	return errs
 }
 
-func (v *podValidator) validateContainerPort(node *yaml.Node) error {
-	if node.Kind != yaml.MappingNode {
-		return &ValidationError{Filename: v.filename, Line: node.Line, Message: "containerPort must be a mapping"}
-	}
+// job is a file for a checker to validate. Archive members are read by
+// the producer of the job, so they carry their content or the error that
+// prevented reading it.
+type job struct {
+	name    string
+	member  bool
+	content []byte
+	err     error
+}
 
-	fields := v.parseMapping(node)
+// checkFiles validates files using up to jobs workers, each with its own
+// checker from newChecker. The results are in the order of files, however
+// the work was scheduled. With failFast, no more files are handed out once
+// one is invalid or ctx is cancelled; files already being checked finish,
+// and the results of the files never checked are left out.
+func checkFiles(ctx context.Context, files []string, jobs int, failFast bool, newChecker func() *checker) []*fileResult {
+	if jobs > len(files) {
+		jobs = len(files)
+	}
+	results, _ := checkJobs(ctx, jobs, failFast, newChecker, func(send func(job) bool) error {
+		for _, file := range files {
+			if !send(job{name: file}) {
+				break
+			}
+		}
+		return nil
+	})
+	return results
+}
 
-	containerPort, ok := fields["containerPort"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: "containerPort is required"}
-	}
-	port, err := v.parseInt(containerPort)
-	if err != nil {
-		return &ValidationError{Filename: v.filename, Line: containerPort.Line, Message: "containerPort must be int"}
-	}
-	if port <= 0 || port >= 65536 {
-		return &ValidationError{Filename: v.filename, Line: containerPort.Line, Message: "containerPort value out of range"}
+// checkJobs validates the files feed sends like checkFiles, and returns the
+// error of feed. send blocks until a worker takes the file, so that feed
+// reads no further ahead than the workers, and returns false once no more
+// files are to be sent.
+func checkJobs(ctx context.Context, jobs int, failFast bool, newChecker func() *checker, feed func(send func(job) bool) error) ([]*fileResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// results grows as files are sent, so workers lock it to fill in the
+	// slots of the files they received.
+	var mu sync.Mutex
+	var results []*fileResult
+	type indexedJob struct {
+		index int
+		job
+	}
+	queue := make(chan indexedJob)
+	var wg sync.WaitGroup
+	for i := 0; i < jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := newChecker()
+			for j := range queue {
+				// A file received just as another failed is left out,
+				// like those never sent.
+				if failFast && ctx.Err() != nil {
+					continue
+				}
+				result := c.check(j.job)
+				mu.Lock()
+				results[j.index] = result
+				mu.Unlock()
+				if failFast && !result.valid() {
+					cancel()
+				}
+			}
+		}()
 	}
 
-	if proto, ok := fields["protocol"]; ok {
-		if proto.Kind != yaml.ScalarNode {
-			return &ValidationError{Filename: v.filename, Line: proto.Line, Message: "protocol must be string"}
+	err := feed(func(j job) bool {
+		if ctx.Err() != nil {
+			return false
 		}
-		if !validProtocols[proto.Value] {
-			return &ValidationError{Filename: v.filename, Line: proto.Line, Message: "protocol has unsupported value '" + proto.Value + "'"}
+		mu.Lock()
+		index := len(results)
+		results = append(results, nil)
+		mu.Unlock()
+		select {
+		case queue <- indexedJob{index, j}:
+			return true
+		case <-ctx.Done():
+			mu.Lock()
+			results = results[:index]
+			mu.Unlock()
+			return false
+		}
+	})
+	close(queue)
+	wg.Wait()
+
+	checked := results[:0]
+	for _, result := range results {
+		if result != nil {
+			checked = append(checked, result)
 		}
 	}
+	return checked, err
+}
 
-	return nil
+// checker validates files one after another. It reads every file into the
+// same buffer, which saves an allocation per file when walking large
+// directories.
+type checker struct {
+	// all keeps every validation error instead of only the first one.
+	all bool
+	// warnAsError reports warnings as errors.
+	warnAsError bool
+	// fix rewrites files with the fixes of validator.FixMemoryUnits before
+	// validating them.
+	fix bool
+	// canonical keeps the canonical form of valid files.
+	canonical bool
+	// baseline holds known errors, which are dropped.
+	baseline baseline
+	opts     validator.Options
+	buf      bytes.Buffer
 }
 
-func (v *podValidator) validateProbe(node *yaml.Node, probeName string) error {
-	fields := v.parseMapping(node)
+// check reads and validates a single file. Unless all is set, only the
+// first validation error is kept, as it always was; warnings are only kept
+// in its place if there are no errors.
+func (c *checker) check(j job) *fileResult {
+	filename := j.name
+	result := &fileResult{filename: filename}
 
-	httpGet, ok := fields["httpGet"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: probeName + ".httpGet is required"}
+	if err := c.read(j); err != nil {
+		result.err = err
+		result.broken = true
+		return result
 	}
-	if httpGet.Kind != yaml.MappingNode {
-		return &ValidationError{Filename: v.filename, Line: httpGet.Line, Message: probeName + ".httpGet must be a mapping"}
+	if c.fix {
+		if err := c.fixFile(filename, result); err != nil {
+			result.err = err
+			result.broken = true
+			return result
+		}
 	}
 
-	httpFields := v.parseMapping(httpGet)
+	// Check does not keep content, so the buffer can be reused for the
+	// next file.
+	checked := validator.Check(c.buf.Bytes(), filename, c.opts)
+	result.containers = checked.Containers
+	for _, e := range checked.Errors {
+		if e.Rule == validator.RuleSyntax {
+			result.broken = true
+		}
+	}
+	errs := c.baseline.filter(checked.Errors)
+	if len(errs) == 0 {
+		if c.canonical {
+			result.canonical, result.err = validator.Canonical(c.buf.Bytes())
+		}
+		return result
+	}
+	for _, e := range errs {
+		if c.warnAsError {
+			e.Severity = validator.SeverityError
+		}
+		if e.IsWarning() {
+			result.warnings++
+		}
+	}
+	if !c.all {
+		errs = firstError(errs)
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Line != errs[j].Line {
+			return errs[i].Line < errs[j].Line
+		}
+		return errs[i].Column < errs[j].Column
+	})
+	result.errs = errs
+	if c.canonical && result.valid() {
+		result.canonical, result.err = validator.Canonical(c.buf.Bytes())
+	}
+	return result
+}
 
-	path, ok := httpFields["path"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: probeName + ".httpGet.path is required"}
+// read replaces the contents of the buffer with those of the file of j.
+func (c *checker) read(j job) error {
+	if j.member {
+		if j.err != nil {
+			return j.err
+		}
+		c.buf.Reset()
+		c.buf.Write(j.content)
+		return nil
 	}
-	if path.Kind != yaml.ScalarNode || !strings.HasPrefix(path.Value, "/") {
-		return &ValidationError{Filename: v.filename, Line: path.Line, Message: probeName + ".httpGet.path must be absolute path"}
+	f, err := os.Open(j.name)
+	if err != nil {
+		return err
 	}
+	defer f.Close()
+	c.buf.Reset()
+	_, err = c.buf.ReadFrom(f)
+	return err
+}
 
-	portNode, ok := httpFields["port"]
-	if !ok {
-		return &ValidationError{Filename: v.filename, Message: probeName + ".httpGet.port is required"}
+// fixFile applies validator.FixMemoryUnits to the buffer and writes it back
+// to filename if anything changed. The fixes are recorded in result.
+func (c *checker) fixFile(filename string, result *fileResult) error {
+	fixed, fixes := validator.FixMemoryUnits(c.buf.Bytes())
+	if len(fixes) == 0 {
+		return nil
 	}
-	port, err := v.parseInt(portNode)
+	info, err := os.Stat(filename)
 	if err != nil {
-		return &ValidationError{Filename: v.filename, Line: portNode.Line, Message: probeName + ".httpGet.port must be int"}
+		return err
 	}
-	if port <= 0 || port >= 65536 {
-		return &ValidationError{Filename: v.filename, Line: portNode.Line, Message: probeName + ".httpGet.port value out of range"}
+	if err := os.WriteFile(filename, fixed, info.Mode().Perm()); err != nil {
+		return err
 	}
-
+	c.buf.Reset()
+	c.buf.Write(fixed)
+	result.fixes = fixes
 	return nil
 }
 
-func (v *podValidator) validateResourceRequirements(node *yaml.Node) error {
-	fields := v.parseMapping(node)
-
-	if req, ok := fields["requests"]; ok {
-		if req.Kind != yaml.MappingNode {
-			return &ValidationError{Filename: v.filename, Line: req.Line, Message: "resources.requests must be a mapping"}
-		}
-		if err := v.validateResourceMap(req, "requests"); err != nil {
-			return err
+// firstError returns the first error in errs, or the first warning if there
+// are no errors.
+func firstError(errs []*validator.ValidationError) []*validator.ValidationError {
+	for _, e := range errs {
+		if !e.IsWarning() {
+			return []*validator.ValidationError{e}
 		}
 	}
+	return errs[:1]
+}
 
-	if lim, ok := fields["limits"]; ok {
-		if lim.Kind != yaml.MappingNode {
-			return &ValidationError{Filename: v.filename, Line: lim.Line, Message: "resources.limits must be a mapping"}
+// limitErrors drops the errors past the first limit across all results, in
+// order. It reports whether any were dropped. A limit of 0 means no limit.
+func limitErrors(results []*fileResult, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	limited := false
+	remaining := limit
+	for _, r := range results {
+		if len(r.errs) > remaining {
+			r.errs = r.errs[:remaining]
+			limited = true
 		}
-		if err := v.validateResourceMap(lim, "limits"); err != nil {
-			return err
+		remaining -= len(r.errs)
+		if r.err != nil {
+			if remaining == 0 {
+				r.err = nil
+				limited = true
+			} else {
+				remaining--
+			}
 		}
 	}
-
-	return nil
+	return limited
 }
 
-func (v *podValidator) validateResourceMap(node *yaml.Node, section string) error {
-	for i := 0; i < len(node.Content); i += 2 {
-		keyNode := node.Content[i]
-		valueNode := node.Content[i+1]
+// ANSI escape sequences used by the colorized text output.
+const (
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
 
-		if keyNode.Kind != yaml.ScalarNode {
-			return &ValidationError{Filename: v.filename, Line: keyNode.Line, Message: "resources." + section + " keys must be strings"}
+// writeText writes one error per line. With color, the location is printed
+// in bold and the message in red, or yellow for warnings. If explain is not
+// nil, each message is followed by the hint it returns for the rule. With
+// verbose, valid files are listed as OK.
+func writeText(w io.Writer, results []*fileResult, color bool, explain func(rule string) string, verbose bool) {
+	for _, r := range results {
+		for _, f := range r.fixes {
+			fmt.Fprintf(w, "%s:%d fixed memory '%s' to '%s'\n", r.filename, f.Line, f.Old, f.New)
 		}
-
-		key := keyNode.Value
-		if !validResourceKeys[key] {
-			return &ValidationError{Filename: v.filename, Line: keyNode.Line, Message: "resources." + section + " has unsupported resource '" + key + "'"}
+		for _, e := range r.errs {
+			if explain != nil {
+				e = withHint(e, explain)
+			}
+			if !color {
+				fmt.Fprintln(w, e)
+				continue
+			}
+			location := e.Filename
+			if e.Line > 0 {
+				location = e.Filename + ":" + e.Position()
+			}
+			if e.IsWarning() {
+				fmt.Fprintf(w, "%s%s%s %swarning: %s%s\n", ansiBold, location, ansiReset, ansiYellow, e.Message, ansiReset)
+			} else {
+				fmt.Fprintf(w, "%s%s%s %s%s%s\n", ansiBold, location, ansiReset, ansiRed, e.Message, ansiReset)
+			}
+		}
+		if r.err != nil {
+			if color {
+				fmt.Fprintf(w, "%s%s:%s %s%v%s\n", ansiBold, r.filename, ansiReset, ansiRed, r.err, ansiReset)
+			} else {
+				fmt.Fprintf(w, "%s: %v\n", r.filename, r.err)
+			}
+		}
+		if verbose && r.valid() {
+			fmt.Fprintf(w, "%s: OK (%s, %s)\n", r.filename, plural(r.containers, "container"), plural(r.warnings, "warning"))
 		}
+	}
+}
 
-		switch key {
-		case "cpu":
-			if valueNode.Kind != yaml.ScalarNode {
-				return &ValidationError{Filename: v.filename, Line: valueNode.Line, Message: "resources." + section + ".cpu must be int"}
+// writeCounts writes the totals of results on a single line.
+func writeCounts(w io.Writer, results []*fileResult, invalid int) {
+	errorCount, warningCount := 0, 0
+	for _, r := range results {
+		for _, e := range r.allErrors() {
+			if e.IsWarning() {
+				warningCount++
+			} else {
+				errorCount++
 			}
-			if _, err := v.parseInt(valueNode); err != nil {
-				return &ValidationError{Filename: v.filename, Line: valueNode.Line, Message: "resources." + section + ".cpu must be int"}
+		}
+	}
+	fmt.Fprintf(w, "errors=%d warnings=%d files=%d invalid=%d\n", errorCount, warningCount, len(results), invalid)
+}
+
+// writeTable writes the errors grouped by file: the name of each file with
+// errors as a header, then one indented row per error, and finally the
+// totals. Hints are added as by writeText.
+func writeTable(w io.Writer, results []*fileResult, explain func(rule string) string) {
+	errorCount, warningCount, fileCount := 0, 0, 0
+	for _, r := range results {
+		errs := r.allErrors()
+		if len(errs) == 0 {
+			continue
+		}
+		fileCount++
+		fmt.Fprintln(w, r.filename)
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		for _, e := range errs {
+			if explain != nil {
+				e = withHint(e, explain)
 			}
-		case "memory":
-			if valueNode.Kind != yaml.ScalarNode {
-				return &ValidationError{Filename: v.filename, Line: valueNode.Line, Message: "resources." + section + ".memory must be string"}
+			position := "-"
+			if e.Line > 0 {
+				position = e.Position()
 			}
-			if !memoryUnitRegex.MatchString(valueNode.Value) {
-				return &ValidationError{Filename: v.filename, Line: valueNode.Line, Message: "resources." + section + ".memory has invalid format '" + valueNode.Value + "'"}
+			message := e.Message
+			if e.IsWarning() {
+				warningCount++
+				message = "warning: " + message
+			} else {
+				errorCount++
 			}
+			fmt.Fprintf(tw, "  %s\t%s\n", position, message)
 		}
+		tw.Flush()
+		fmt.Fprintln(w)
 	}
-	return nil
+	fmt.Fprintf(w, "%s and %s in %s\n", plural(errorCount, "error"), plural(warningCount, "warning"), plural(fileCount, "file"))
 }
 
-func (v *podValidator) parseInt(node *yaml.Node) (int, error) {
-	if node.Kind != yaml.ScalarNode {
-		return 0, fmt.Errorf("not a scalar")
+// plural formats n followed by noun, adding an s unless n is 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
 	}
-	i, err := strconv.Atoi(node.Value)
-	if err != nil {
-		return 0, err
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// writeCanonical writes the canonical form of every valid file, separating
+// the files like the documents of a single YAML stream.
+func writeCanonical(w io.Writer, results []*fileResult) {
+	first := true
+	for _, r := range results {
+		if r.canonical == nil {
+			continue
+		}
+		if !first {
+			fmt.Fprintln(w, "---")
+		}
+		first = false
+		w.Write(r.canonical)
 	}
-	return i, nil
 }
 
-func (v *podValidator) parseMapping(node *yaml.Node) map[string]*yaml.Node {
-	result := make(map[string]*yaml.Node)
-	if node.Kind != yaml.MappingNode {
-		return result
+// withHint returns a copy of e with the hint explain returns for its rule
+// appended to the message.
+func withHint(e *validator.ValidationError, explain func(rule string) string) *validator.ValidationError {
+	hint := explain(e.Rule)
+	if hint == "" {
+		return e
 	}
-	for i := 0; i < len(node.Content); i += 2 {
-		key := node.Content[i]
-		value := node.Content[i+1]
-		if key.Kind == yaml.ScalarNode {
-			result[key.Value] = value
-		}
+	explained := *e
+	explained.Message += " (" + hint + ")"
+	return &explained
+}
+
+// isTerminal reports whether f is connected to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
 	}
-	return result
+	return info.Mode()&os.ModeCharDevice != 0
 }
 
-func exitWithError(filename string, line int, msg string) {
-	if line > 0 {
-		fmt.Fprintf(os.Stderr, "%s:%d %s\n", filename, line, msg)
-	} else {
-		fmt.Fprintf(os.Stderr, "%s %s\n", filename, msg)
+// writeJSON writes all errors as a single JSON array. Read failures are
+// included as errors without a line.
+func writeJSON(w io.Writer, results []*fileResult) error {
+	errs := make([]*validator.ValidationError, 0)
+	for _, r := range results {
+		errs = append(errs, r.allErrors()...)
 	}
-	os.Exit(1)
-}
\ No newline at end of file
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(errs)
+}
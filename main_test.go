@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFormatFlag(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "pod.yaml")
+	if err := os.WriteFile(manifest, []byte(benchmarkManifest), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{"-format=table", manifest}, exitValid},
+		{[]string{"-format", "json", manifest}, exitValid},
+		{[]string{"-format=xml", manifest}, exitError},
+		{[]string{"-output=xml", manifest}, exitError},
+	}
+	for _, tt := range tests {
+		if got := runValidate(append([]string{"-quiet"}, tt.args...)); got != tt.want {
+			t.Errorf("runValidate(%q) = %d, want %d", tt.args, got, tt.want)
+		}
+	}
+}
+
+// stderrOf runs runValidate with args and returns its exit status and what
+// it wrote to stderr.
+func stderrOf(t *testing.T, args ...string) (int, string) {
+	t.Helper()
+	f, err := os.CreateTemp(t.TempDir(), "stderr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	stderr := os.Stderr
+	os.Stderr = f
+	code := runValidate(args)
+	os.Stderr = stderr
+	out, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, string(out)
+}
+
+func TestContinueFlag(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("kind: Pod\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "2 of 2 files invalid\n"},
+		{[]string{"-continue"}, "2 of 2 files invalid\n"},
+		{[]string{"-continue=false"}, "1 of 1 files invalid\nstopped at the first invalid file; 1 file not checked\n"},
+	}
+	for _, tt := range tests {
+		code, out := stderrOf(t, append(append([]string{"-color=never", "-j", "1"}, tt.args...), dir)...)
+		if code != exitInvalid || !strings.HasSuffix(out, tt.want) {
+			t.Errorf("runValidate(%q) = %d, %q; want %d ending in %q", tt.args, code, out, exitInvalid, tt.want)
+		}
+	}
+}
+
+const benchmarkManifest = `apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  labels: {app: web}
+spec:
+  containers:
+    - name: app
+      image: registry.bigbrother.io/app:1.0
+      ports:
+        - {name: http, containerPort: 8080, protocol: TCP}
+      readinessProbe:
+        httpGet: {path: /healthz, port: http}
+      resources:
+        requests: {cpu: 100m, memory: 64Mi}
+        limits: {cpu: 1, memory: 128Mi}
+`
+
+// BenchmarkValidateDir validates a directory of 100 manifests with a single
+// worker. shared-buffer is what validate does; buffer-per-file reads every
+// file into a fresh buffer, for comparison.
+func BenchmarkValidateDir(b *testing.B) {
+	dir := b.TempDir()
+	for i := 0; i < 100; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("pod-%03d.yaml", i))
+		if err := os.WriteFile(name, []byte(benchmarkManifest), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	files, _, err := findManifests(dir, nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("shared-buffer", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, r := range checkFiles(context.Background(), files, 1, false, func() *checker { return &checker{all: true} }) {
+				if !r.valid() {
+					b.Fatalf("%s: %v", r.filename, r.allErrors())
+				}
+			}
+		}
+	})
+	b.Run("buffer-per-file", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, file := range files {
+				if r := (&checker{all: true}).check(job{name: file}); !r.valid() {
+					b.Fatalf("%s: %v", r.filename, r.allErrors())
+				}
+			}
+		}
+	})
+}
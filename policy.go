@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+
+	"yamlvalid/validator"
+)
+
+// policy is an organisation's validation policy as read from a -policy file.
+// Every field is optional:
+//
+//	registries: [registry.example.com]
+//	forbiddenTags: [latest, dev]
+//	requireDigest: false
+//	requireRequests: true
+//	namePattern: '^[a-z][a-z0-9-]*$'
+//	memoryGranularity: 128Mi
+//	extendedResources: [nvidia.com/gpu]
+type policy struct {
+	Registries        []string `yaml:"registries"`
+	ForbiddenTags     []string `yaml:"forbiddenTags"`
+	RequireDigest     bool     `yaml:"requireDigest"`
+	RequireRequests   bool     `yaml:"requireRequests"`
+	NamePattern       string   `yaml:"namePattern"`
+	MemoryGranularity string   `yaml:"memoryGranularity"`
+	ExtendedResources []string `yaml:"extendedResources"`
+}
+
+// loadPolicy reads and parses a policy file. Unknown keys are rejected so
+// that a misspelt setting does not silently relax the policy.
+func loadPolicy(filename string) (*policy, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var p policy
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+	return &p, nil
+}
+
+// apply sets the options the policy defines. A forbidden tag of latest sets
+// NoLatest rather than being listed, so that -no-latest can override it.
+func (p *policy) apply(opts *validator.Options) error {
+	opts.Registries = p.Registries
+	opts.ForbiddenTags = nil
+	for _, tag := range p.ForbiddenTags {
+		if tag == "latest" {
+			opts.NoLatest = true
+		} else {
+			opts.ForbiddenTags = append(opts.ForbiddenTags, tag)
+		}
+	}
+	opts.RequireDigest = p.RequireDigest
+	opts.RequireRequests = p.RequireRequests
+	opts.MemoryGranularity = p.MemoryGranularity
+	opts.ExtendedResources = p.ExtendedResources
+	if p.NamePattern != "" {
+		re, err := regexp.Compile(p.NamePattern)
+		if err != nil {
+			return fmt.Errorf("invalid namePattern: %w", err)
+		}
+		opts.NamePattern = re
+	}
+	return nil
+}
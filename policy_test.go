@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNoLatestOverridesPolicy(t *testing.T) {
+	dir := t.TempDir()
+	policyFile := filepath.Join(dir, "policy.yaml")
+	if err := os.WriteFile(policyFile, []byte("forbiddenTags: [latest, dev]\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	manifest := func(tag string) string {
+		name := filepath.Join(dir, tag+".yaml")
+		content := "apiVersion: v1\nkind: Pod\nmetadata:\n  name: web\nspec:\n  containers:\n    - name: app\n      image: registry.bigbrother.io/app:" + tag + "\n      resources:\n        limits: {cpu: 1, memory: 128Mi}\n"
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{[]string{manifest("latest")}, exitInvalid},
+		{[]string{"-no-latest=false", manifest("latest")}, exitValid},
+		{[]string{"-no-latest=false", manifest("dev")}, exitInvalid},
+		{[]string{"-no-latest", manifest("latest")}, exitInvalid},
+	}
+	for _, tt := range tests {
+		args := append([]string{"-quiet", "-policy", policyFile}, tt.args...)
+		if got := runValidate(args); got != tt.want {
+			t.Errorf("runValidate(%q) = %d, want %d", args, got, tt.want)
+		}
+	}
+}
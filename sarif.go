@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+
+	"yamlvalid/validator"
+)
+
+const (
+	sarifVersion = "2.1.0"
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	toolName     = "yamlvalid"
+)
+
+// The types below cover the subset of SARIF 2.1.0 needed to report
+// validation errors to code scanning tools.
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	ShortDescription sarifMessage  `json:"shortDescription"`
+	Help             *sarifMessage `json:"help,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// writeSARIF writes all errors as a SARIF log with a single run. Only the
+// rules that actually produced results are listed in the tool driver, as
+// configured by opts.
+func writeSARIF(w io.Writer, results []*fileResult, opts validator.Options) error {
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: toolName, Rules: []sarifRule{}}},
+		Results: []sarifResult{},
+	}
+
+	seenRules := make(map[string]bool)
+	for _, r := range results {
+		for _, e := range r.allErrors() {
+			seenRules[e.Rule] = true
+			run.Results = append(run.Results, newSARIFResult(e))
+		}
+	}
+
+	for id := range seenRules {
+		// Rules registered by an embedding program have no description.
+		description := opts.Describe(id)
+		if description == "" {
+			description = id
+		}
+		rule := sarifRule{
+			ID:               id,
+			ShortDescription: sarifMessage{Text: description},
+		}
+		if hint := opts.Explain(id); hint != "" {
+			rule.Help = &sarifMessage{Text: hint}
+		}
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+	}
+	sort.Slice(run.Tool.Driver.Rules, func(i, j int) bool {
+		return run.Tool.Driver.Rules[i].ID < run.Tool.Driver.Rules[j].ID
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Version: sarifVersion, Schema: sarifSchema, Runs: []sarifRun{run}})
+}
+
+func newSARIFResult(e *validator.ValidationError) sarifResult {
+	location := sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: e.Filename}}
+	// SARIF lines are 1-based; errors without a line are reported file-wide.
+	if e.Line > 0 {
+		location.Region = &sarifRegion{StartLine: e.Line, StartColumn: e.Column}
+	}
+	level := "error"
+	if e.IsWarning() {
+		level = "warning"
+	}
+	return sarifResult{
+		RuleID:    e.Rule,
+		Level:     level,
+		Message:   sarifMessage{Text: e.Message},
+		Locations: []sarifLocation{{PhysicalLocation: location}},
+	}
+}
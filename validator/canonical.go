@@ -0,0 +1,67 @@
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Canonical re-encodes every document of content with the keys of each
+// mapping sorted, block style throughout and 2-space indentation, so that
+// two versions of a manifest can be compared with diff. Aliases and merge
+// keys are expanded. Scalars keep their quoting, which decides their type.
+func Canonical(content []byte) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var root yaml.Node
+		if err := decoder.Decode(&root); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if len(root.Content) == 0 || isEmptyDocument(root.Content[0]) {
+			continue
+		}
+		canonicalize(resolveAliases(&root))
+		if err := encoder.Encode(&root); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// canonicalize sorts the keys of every mapping under node, switches flow
+// collections to block style and drops anchors, which have been resolved.
+func canonicalize(node *yaml.Node) {
+	node.Anchor = ""
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style &^= yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		canonicalize(child)
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+
+	pairs := make([][2]*yaml.Node, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		pairs = append(pairs, [2]*yaml.Node{node.Content[i], node.Content[i+1]})
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i][0].Value < pairs[j][0].Value
+	})
+	for i, pair := range pairs {
+		node.Content[2*i], node.Content[2*i+1] = pair[0], pair[1]
+	}
+}
@@ -0,0 +1,186 @@
+package validator
+
+// Fields each object may carry according to the Kubernetes API. They are
+// used in strict mode to catch misspelt or misplaced keys, so they list every
+// field the API accepts, not only the ones this validator checks.
+var (
+	topLevelFields = fieldSet(
+		"apiVersion", "kind", "metadata", "spec", "status",
+	)
+	listFields = fieldSet(
+		"apiVersion", "kind", "metadata", "items",
+	)
+	objectMetaFields = fieldSet(
+		"name", "generateName", "namespace", "labels", "annotations",
+		"uid", "resourceVersion", "generation", "creationTimestamp",
+		"deletionTimestamp", "deletionGracePeriodSeconds",
+		"ownerReferences", "finalizers", "managedFields",
+	)
+	deploymentSpecFields = fieldSet(
+		"replicas", "selector", "template", "strategy", "minReadySeconds",
+		"revisionHistoryLimit", "paused", "progressDeadlineSeconds",
+	)
+	labelSelectorFields = fieldSet(
+		"matchLabels", "matchExpressions",
+	)
+	labelSelectorRequirementFields = fieldSet(
+		"key", "operator", "values",
+	)
+	podTemplateFields = fieldSet(
+		"metadata", "spec",
+	)
+	podSpecFields = fieldSet(
+		"volumes", "initContainers", "containers", "ephemeralContainers",
+		"restartPolicy", "terminationGracePeriodSeconds",
+		"activeDeadlineSeconds", "dnsPolicy", "nodeSelector",
+		"serviceAccountName", "serviceAccount",
+		"automountServiceAccountToken", "nodeName", "hostNetwork",
+		"hostPID", "hostIPC", "shareProcessNamespace", "securityContext",
+		"imagePullSecrets", "hostname", "subdomain", "affinity",
+		"schedulerName", "tolerations", "hostAliases", "priorityClassName",
+		"priority", "dnsConfig", "readinessGates", "runtimeClassName",
+		"enableServiceLinks", "preemptionPolicy", "overhead",
+		"topologySpreadConstraints", "setHostnameAsFQDN", "os", "hostUsers",
+		"schedulingGates", "resourceClaims",
+	)
+	podOSFields = fieldSet(
+		"name",
+	)
+	affinityFields = fieldSet(
+		"nodeAffinity", "podAffinity", "podAntiAffinity",
+	)
+	nodeAffinityFields = fieldSet(
+		"requiredDuringSchedulingIgnoredDuringExecution",
+		"preferredDuringSchedulingIgnoredDuringExecution",
+	)
+	nodeSelectorFields = fieldSet(
+		"nodeSelectorTerms",
+	)
+	podAffinityFields = fieldSet(
+		"requiredDuringSchedulingIgnoredDuringExecution",
+		"preferredDuringSchedulingIgnoredDuringExecution",
+	)
+	dnsConfigFields = fieldSet(
+		"nameservers", "searches", "options",
+	)
+	dnsConfigOptionFields = fieldSet(
+		"name", "value",
+	)
+	localObjectReferenceFields = fieldSet(
+		"name",
+	)
+	hostAliasFields = fieldSet(
+		"ip", "hostnames",
+	)
+	hostPathVolumeFields = fieldSet(
+		"path", "type",
+	)
+	emptyDirVolumeFields = fieldSet(
+		"medium", "sizeLimit",
+	)
+	configMapVolumeFields = fieldSet(
+		"name", "items", "defaultMode", "optional",
+	)
+	secretVolumeFields = fieldSet(
+		"secretName", "items", "defaultMode", "optional",
+	)
+	pvcVolumeFields = fieldSet(
+		"claimName", "readOnly",
+	)
+	tolerationFields = fieldSet(
+		"key", "operator", "value", "effect", "tolerationSeconds",
+	)
+	volumeFields    = fieldSet(append([]string{"name"}, volumeSources...)...)
+	containerFields = fieldSet(
+		"name", "image", "command", "args", "workingDir", "ports", "envFrom",
+		"env", "resources", "resizePolicy", "restartPolicy", "volumeMounts",
+		"volumeDevices", "livenessProbe", "readinessProbe", "startupProbe",
+		"lifecycle", "terminationMessagePath", "terminationMessagePolicy",
+		"imagePullPolicy", "securityContext", "stdin", "stdinOnce", "tty",
+	)
+	podSecurityContextFields = fieldSet(
+		"seLinuxOptions", "windowsOptions", "runAsUser", "runAsGroup",
+		"runAsNonRoot", "supplementalGroups", "supplementalGroupsPolicy",
+		"fsGroup", "sysctls", "fsGroupChangePolicy", "seccompProfile",
+		"appArmorProfile", "seLinuxChangePolicy",
+	)
+	securityContextFields = fieldSet(
+		"capabilities", "privileged", "seLinuxOptions", "windowsOptions",
+		"runAsUser", "runAsGroup", "runAsNonRoot", "readOnlyRootFilesystem",
+		"allowPrivilegeEscalation", "procMount", "seccompProfile",
+		"appArmorProfile",
+	)
+	envVarFields = fieldSet(
+		"name", "value", "valueFrom",
+	)
+	envVarSourceFields = fieldSet(
+		"fieldRef", "resourceFieldRef", "configMapKeyRef", "secretKeyRef",
+	)
+	objectFieldSelectorFields = fieldSet(
+		"apiVersion", "fieldPath",
+	)
+	resourceFieldSelectorFields = fieldSet(
+		"containerName", "resource", "divisor",
+	)
+	keySelectorFields = fieldSet(
+		"name", "key", "optional",
+	)
+	envFromSourceFields = fieldSet(
+		"prefix", "configMapRef", "secretRef",
+	)
+	envSourceRefFields = fieldSet(
+		"name", "optional",
+	)
+	volumeMountFields = fieldSet(
+		"name", "readOnly", "recursiveReadOnly", "mountPath", "subPath",
+		"mountPropagation", "subPathExpr",
+	)
+	containerPortFields = fieldSet(
+		"name", "hostPort", "containerPort", "protocol", "hostIP",
+	)
+	probeFields = fieldSet(
+		"exec", "httpGet", "tcpSocket", "grpc", "initialDelaySeconds",
+		"timeoutSeconds", "periodSeconds", "successThreshold",
+		"failureThreshold", "terminationGracePeriodSeconds",
+	)
+	lifecycleFields = fieldSet(
+		"postStart", "preStop", "stopSignal",
+	)
+	lifecycleHandlerFields = fieldSet(
+		"exec", "httpGet", "tcpSocket", "sleep",
+	)
+	httpGetFields = fieldSet(
+		"path", "port", "host", "scheme", "httpHeaders",
+	)
+	httpHeaderFields = fieldSet(
+		"name", "value",
+	)
+	tcpSocketFields = fieldSet(
+		"port", "host",
+	)
+	execFields = fieldSet(
+		"command",
+	)
+	resourceRequirementsFields = fieldSet(
+		"limits", "requests", "claims",
+	)
+)
+
+// volumeSources lists the fields of a volume that each name a kind of
+// volume source. A volume sets exactly one of them.
+var volumeSources = []string{
+	"hostPath", "emptyDir", "gcePersistentDisk", "awsElasticBlockStore",
+	"secret", "nfs", "iscsi", "glusterfs", "persistentVolumeClaim", "rbd",
+	"flexVolume", "cinder", "cephfs", "flocker", "downwardAPI", "fc",
+	"azureFile", "configMap", "vsphereVolume", "quobyte", "azureDisk",
+	"photonPersistentDisk", "projected", "portworxVolume", "scaleIO",
+	"storageos", "csi", "ephemeral", "image",
+}
+
+func fieldSet(names ...string) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+	return set
+}
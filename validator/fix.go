@@ -0,0 +1,97 @@
+package validator
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fix describes a change made by FixMemoryUnits.
+type Fix struct {
+	Line int
+	Old  string
+	New  string
+}
+
+// memoryUnitFixRegex matches memory quantities whose unit is spelt with the
+// wrong case, such as 1gi or 512MI.
+var memoryUnitFixRegex = regexp.MustCompile(`^(\d+)([kKmMgG][iI]?)$`)
+
+// FixMemoryUnits rewrites the memory requests and limits in content whose
+// unit has the wrong case, such as 1gi, to the canonical spelling, such as
+// 1Gi. Values are replaced where they stand, so comments and formatting are
+// kept. content itself is not modified. Content that cannot be parsed is
+// returned unchanged.
+func FixMemoryUnits(content []byte) ([]byte, []Fix) {
+	var nodes []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var root yaml.Node
+		if decoder.Decode(&root) != nil {
+			break
+		}
+		collectMemoryValues(&root, "", &nodes)
+	}
+
+	// The lines share memory with the slice they were split from, so split
+	// a copy to leave the caller's content untouched.
+	lines := bytes.Split(bytes.Clone(content), []byte("\n"))
+	var fixes []Fix
+	for _, node := range nodes {
+		fixed, ok := canonicalMemory(node.Value)
+		if !ok || fixed == node.Value {
+			continue
+		}
+		// Quoted values start one column after the quote.
+		start := node.Column - 1
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+			start++
+		}
+		line := lines[node.Line-1]
+		if start+len(node.Value) > len(line) || string(line[start:start+len(node.Value)]) != node.Value {
+			continue
+		}
+		copy(line[start:], fixed)
+		fixes = append(fixes, Fix{Line: node.Line, Old: node.Value, New: fixed})
+	}
+	return bytes.Join(lines, []byte("\n")), fixes
+}
+
+// collectMemoryValues appends to nodes the scalar values of memory keys in
+// requests and limits mappings under node.
+func collectMemoryValues(node *yaml.Node, key string, nodes *[]*yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, value := node.Content[i], node.Content[i+1]
+			if k.Value == "memory" && (key == "requests" || key == "limits") && value.Kind == yaml.ScalarNode {
+				*nodes = append(*nodes, value)
+				continue
+			}
+			collectMemoryValues(value, k.Value, nodes)
+		}
+		return
+	}
+	for _, child := range node.Content {
+		collectMemoryValues(child, "", nodes)
+	}
+}
+
+// canonicalMemory returns s with its unit spelt as Kubernetes expects. ok is
+// false if s is not a memory quantity with one of the supported units.
+func canonicalMemory(s string) (string, bool) {
+	m := memoryUnitFixRegex.FindStringSubmatch(s)
+	// A lowercase m means milli, not mega, so 128m cannot be guessed.
+	if m == nil || m[2] == "m" {
+		return "", false
+	}
+	unit := strings.ToUpper(m[2][:1])
+	if unit == "K" {
+		unit = "k"
+	}
+	if len(m[2]) == 2 {
+		unit = strings.ToUpper(m[2][:1]) + "i"
+	}
+	return m[1] + unit, true
+}
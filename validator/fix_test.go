@@ -0,0 +1,21 @@
+package validator
+
+import "testing"
+
+func TestFixMemoryUnits(t *testing.T) {
+	content := []byte("resources:\n  limits:\n    memory: 1gi\n  requests:\n    memory: \"512MI\"\n")
+	original := string(content)
+
+	fixed, fixes := FixMemoryUnits(content)
+
+	want := "resources:\n  limits:\n    memory: 1Gi\n  requests:\n    memory: \"512Mi\"\n"
+	if string(fixed) != want {
+		t.Errorf("FixMemoryUnits() = %q, want %q", fixed, want)
+	}
+	if len(fixes) != 2 {
+		t.Errorf("FixMemoryUnits() made %d fixes, want 2", len(fixes))
+	}
+	if string(content) != original {
+		t.Errorf("FixMemoryUnits() modified its input to %q", content)
+	}
+}
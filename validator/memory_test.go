@@ -0,0 +1,48 @@
+package validator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMemory(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"128Ki", 128 << 10},
+		{"512Mi", 512 << 20},
+		{"2Gi", 2 << 30},
+		{"500k", 500e3},
+		{"64M", 64e6},
+		{"3G", 3e9},
+		{"0Mi", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseMemory(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMemory(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseMemoryInvalid(t *testing.T) {
+	for _, in := range []string{"", "128", "128mi", "1.5Gi", "-1Gi", "Gi", "1Ti"} {
+		if _, err := ParseMemory(in); err == nil {
+			t.Errorf("ParseMemory(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestParseMemoryOverflow(t *testing.T) {
+	// 8589934592Gi is 2^63 bytes, one more than fits in an int64, and the
+	// number alone overflows for the second input.
+	for _, in := range []string{"8589934592Gi", "9223372036854775808k"} {
+		if _, err := ParseMemory(in); !errors.Is(err, errMemoryOverflow) {
+			t.Errorf("ParseMemory(%q) error = %v, want %v", in, err, errMemoryOverflow)
+		}
+	}
+	if got, err := ParseMemory("8589934591Gi"); err != nil || got != 8589934591<<30 {
+		t.Errorf("ParseMemory(8589934591Gi) = %d, %v, want %d", got, err, int64(8589934591)<<30)
+	}
+}
@@ -0,0 +1,70 @@
+package validator
+
+import "gopkg.in/yaml.v3"
+
+// resolveAliases replaces every alias under node by the node it refers to
+// and expands merge keys (<<: *defaults) into the mappings that contain
+// them, so that the validators see the fields as Kubernetes would. Keys set
+// in the mapping itself take precedence over merged ones, and of several
+// merged mappings the earlier ones win. The tree is changed in place; nodes
+// reached through several aliases are shared, not copied.
+func resolveAliases(node *yaml.Node) *yaml.Node {
+	return resolveNode(node, make(map[*yaml.Node]bool))
+}
+
+func resolveNode(node *yaml.Node, seen map[*yaml.Node]bool) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if seen[node] {
+		return node
+	}
+	seen[node] = true
+
+	for i, child := range node.Content {
+		node.Content[i] = resolveNode(child, seen)
+	}
+	if node.Kind == yaml.MappingNode {
+		node.Content = mergeKeys(node.Content)
+	}
+	return node
+}
+
+// mergeKeys returns the key/value pairs of a mapping with the merge keys
+// among them replaced by the pairs of the mappings they refer to.
+func mergeKeys(content []*yaml.Node) []*yaml.Node {
+	var merged []*yaml.Node
+	var own []*yaml.Node
+	for i := 0; i+1 < len(content); i += 2 {
+		key, value := content[i], content[i+1]
+		if key.Kind != yaml.ScalarNode || key.Tag != "!!merge" {
+			own = append(own, key, value)
+			continue
+		}
+		switch value.Kind {
+		case yaml.MappingNode:
+			merged = append(merged, value.Content...)
+		case yaml.SequenceNode:
+			for _, item := range value.Content {
+				if item.Kind == yaml.MappingNode {
+					merged = append(merged, item.Content...)
+				}
+			}
+		}
+	}
+	if merged == nil {
+		return content
+	}
+
+	set := make(map[string]bool)
+	for i := 0; i < len(own); i += 2 {
+		set[own[i].Value] = true
+	}
+	for i := 0; i+1 < len(merged); i += 2 {
+		if key := merged[i]; !set[key.Value] {
+			set[key.Value] = true
+			own = append(own, key, merged[i+1])
+		}
+	}
+	return own
+}
@@ -0,0 +1,174 @@
+package validator
+
+import (
+	"bytes"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Pod is the typed form of a Pod manifest returned by Parse. It covers the
+// fields the validator checks; everything else is dropped.
+type Pod struct {
+	APIVersion string     `yaml:"apiVersion"`
+	Kind       string     `yaml:"kind"`
+	Metadata   ObjectMeta `yaml:"metadata"`
+	Spec       PodSpec    `yaml:"spec"`
+}
+
+// ObjectMeta is the metadata of a Pod.
+type ObjectMeta struct {
+	Name        string            `yaml:"name"`
+	Namespace   string            `yaml:"namespace"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// PodSpec is the spec of a Pod.
+type PodSpec struct {
+	OS                 *PodOS            `yaml:"os"`
+	RestartPolicy      string            `yaml:"restartPolicy"`
+	ServiceAccountName string            `yaml:"serviceAccountName"`
+	NodeSelector       map[string]string `yaml:"nodeSelector"`
+	HostNetwork        bool              `yaml:"hostNetwork"`
+	HostPID            bool              `yaml:"hostPID"`
+	HostIPC            bool              `yaml:"hostIPC"`
+	DNSPolicy          string            `yaml:"dnsPolicy"`
+	Tolerations        []Toleration      `yaml:"tolerations"`
+	Volumes            []Volume          `yaml:"volumes"`
+	InitContainers     []Container       `yaml:"initContainers"`
+	Containers         []Container       `yaml:"containers"`
+}
+
+// PodOS names the operating system the containers of a Pod run on.
+type PodOS struct {
+	Name string `yaml:"name"`
+}
+
+// Toleration lets a Pod be scheduled onto nodes with matching taints.
+type Toleration struct {
+	Key               string `yaml:"key"`
+	Operator          string `yaml:"operator"`
+	Value             string `yaml:"value"`
+	Effect            string `yaml:"effect"`
+	TolerationSeconds *int64 `yaml:"tolerationSeconds"`
+}
+
+// Volume holds the name of a volume. Its source is not decoded.
+type Volume struct {
+	Name string `yaml:"name"`
+}
+
+// Container is a container or init container of a Pod.
+type Container struct {
+	Name            string               `yaml:"name"`
+	Image           string               `yaml:"image"`
+	ImagePullPolicy string               `yaml:"imagePullPolicy"`
+	Command         []string             `yaml:"command"`
+	Args            []string             `yaml:"args"`
+	Ports           []ContainerPort      `yaml:"ports"`
+	Env             []EnvVar             `yaml:"env"`
+	VolumeMounts    []VolumeMount        `yaml:"volumeMounts"`
+	ReadinessProbe  *Probe               `yaml:"readinessProbe"`
+	LivenessProbe   *Probe               `yaml:"livenessProbe"`
+	StartupProbe    *Probe               `yaml:"startupProbe"`
+	Resources       ResourceRequirements `yaml:"resources"`
+}
+
+// ContainerPort is a port exposed by a container.
+type ContainerPort struct {
+	Name          string `yaml:"name"`
+	ContainerPort int    `yaml:"containerPort"`
+	HostPort      int    `yaml:"hostPort"`
+	HostIP        string `yaml:"hostIP"`
+	Protocol      string `yaml:"protocol"`
+}
+
+// EnvVar holds an environment variable. Variables set through valueFrom
+// have an empty Value.
+type EnvVar struct {
+	Name  string `yaml:"name"`
+	Value string `yaml:"value"`
+}
+
+// VolumeMount mounts a volume of the Pod into a container.
+type VolumeMount struct {
+	Name      string `yaml:"name"`
+	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly"`
+}
+
+// Probe holds the handler of a probe; exactly one of them is set.
+type Probe struct {
+	HTTPGet   *HTTPGetAction   `yaml:"httpGet"`
+	TCPSocket *TCPSocketAction `yaml:"tcpSocket"`
+	Exec      *ExecAction      `yaml:"exec"`
+}
+
+// HTTPGetAction probes a container with an HTTP GET request. Port is a
+// port number or the name of one of the container's ports.
+type HTTPGetAction struct {
+	Path   string `yaml:"path"`
+	Port   string `yaml:"port"`
+	Scheme string `yaml:"scheme"`
+}
+
+// TCPSocketAction probes a container by opening a TCP connection. Port is
+// a port number or the name of one of the container's ports.
+type TCPSocketAction struct {
+	Port string `yaml:"port"`
+}
+
+// ExecAction probes a container by running a command in it.
+type ExecAction struct {
+	Command []string `yaml:"command"`
+}
+
+// ResourceRequirements maps resource names to quantities as written, such
+// as "500m" or "128Mi".
+type ResourceRequirements struct {
+	Requests map[string]string `yaml:"requests"`
+	Limits   map[string]string `yaml:"limits"`
+}
+
+// Parse validates content, which must hold a single Pod, with opts and
+// decodes it. The errors are those Validate reports. The Pod is nil unless
+// there are no errors; warnings alone do not prevent decoding.
+func Parse(content []byte, opts Options) (*Pod, []*ValidationError) {
+	errs := Validate(content, "", opts)
+	for _, e := range errs {
+		if !e.IsWarning() {
+			return nil, errs
+		}
+	}
+
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var root yaml.Node
+		// Validate has already decoded the stream, so the only error left
+		// is the end of it.
+		if decoder.Decode(&root) != nil {
+			break
+		}
+		if len(root.Content) > 0 && !isEmptyDocument(root.Content[0]) {
+			docs = append(docs, root.Content[0])
+		}
+	}
+
+	v := &podValidator{errs: errs}
+	if len(docs) != 1 {
+		v.addError(RuleUnsupportedValue, nil, "content must hold a single Pod")
+		return nil, v.errs
+	}
+	if kind := v.parseMapping(docs[0])["kind"]; kind.Value != "Pod" {
+		v.addError(RuleUnsupportedValue, kind, "kind has unsupported value '"+kind.Value+"'")
+		return nil, v.errs
+	}
+
+	var pod Pod
+	if err := docs[0].Decode(&pod); err != nil {
+		v.addError(RuleType, nil, err.Error())
+		return nil, v.errs
+	}
+	return &pod, errs
+}
@@ -0,0 +1,200 @@
+package validator
+
+// Rule identifiers attached to validation errors. They are stable, so tools
+// consuming the output can group findings by the check that produced them.
+const (
+	RuleSyntax                 = "syntax"
+	RuleEmptyDocument          = "empty-document"
+	RuleRequired               = "required-field"
+	RuleType                   = "field-type"
+	RuleUnsupportedValue       = "unsupported-value"
+	RuleNotEmpty               = "not-empty"
+	RuleContainerName          = "container-name"
+	RuleContainerNameUnique    = "container-name-unique"
+	RuleImageFormat            = "image-format"
+	RuleImageRegistry          = "image-registry"
+	RuleImageTag               = "image-tag"
+	RulePortRange              = "port-range"
+	RuleProbePath              = "probe-path"
+	RuleResourceName           = "resource-name"
+	RuleMemoryFormat           = "memory-format"
+	RuleCPUFormat              = "cpu-format"
+	RuleRequestsExceedLimits   = "requests-exceed-limits"
+	RuleNonNegative            = "non-negative"
+	RuleEnvName                = "env-name"
+	RuleEnvSource              = "env-source"
+	RuleVolumeNameUnique       = "volume-name-unique"
+	RuleVolumeNotFound         = "volume-not-found"
+	RuleAbsolutePath           = "absolute-path"
+	RuleDuplicateKey           = "duplicate-key"
+	RuleUnknownField           = "unknown-field"
+	RuleLabelFormat            = "label-format"
+	RuleAnnotationKey          = "annotation-key"
+	RuleImageLatest            = "image-latest"
+	RuleProbeHandler           = "probe-handler"
+	RuleNameFormat             = "name-format"
+	RuleMemoryZero             = "memory-zero"
+	RuleHostPort               = "host-port"
+	RuleTolerationValue        = "toleration-value"
+	RuleIPAddress              = "ip-address"
+	RuleDeprecatedField        = "deprecated-field"
+	RulePortName               = "port-name"
+	RulePortNameUnique         = "port-name-unique"
+	RuleHostPortUnique         = "host-port-unique"
+	RuleImageDigest            = "image-digest"
+	RuleVolumeSource           = "volume-source"
+	RulePriorityClass          = "priority-class"
+	RuleMountPropagation       = "mount-propagation"
+	RuleSubPath                = "sub-path"
+	RuleSelectorLabels         = "selector-labels"
+	RulePortNotFound           = "port-not-found"
+	RuleMemoryGranularity      = "memory-granularity"
+	RuleLegacyBool             = "legacy-bool"
+	RulePortProtocol           = "port-protocol"
+	RuleServerManaged          = "server-managed"
+	RuleNameLength             = "name-length"
+	RuleStdinOnce              = "stdin-once"
+	RuleContainerRestartPolicy = "container-restart-policy"
+	RuleSelectorOperator       = "selector-operator"
+)
+
+var ruleDescriptions = map[string]string{
+	RuleSyntax:                 "File must be readable, well-formed YAML",
+	RuleEmptyDocument:          "File must contain at least one YAML document",
+	RuleRequired:               "Required field is missing",
+	RuleType:                   "Field has the wrong type",
+	RuleUnsupportedValue:       "Field value is not one of the supported values",
+	RuleNotEmpty:               "Field must not be empty",
+	RuleContainerName:          "Container name must be snake_case",
+	RuleContainerNameUnique:    "Container names must be unique within a pod",
+	RuleImageFormat:            "Image must be a registry/repository:tag reference with a lowercase repository",
+	RuleImageRegistry:          "Image must come from an allowed registry",
+	RuleImageTag:               "Image must have an explicit, well-formed tag",
+	RulePortRange:              "Port must be between 1 and 65535",
+	RuleProbePath:              "Probe path must be an absolute URL path without query or fragment",
+	RuleResourceName:           "Only cpu, memory, ephemeral-storage and allowed extended resources are supported",
+	RuleMemoryFormat:           "Memory and ephemeral storage must be an integer with a Ki, Mi, Gi, k, M or G suffix",
+	RuleCPUFormat:              "CPU must be a whole number of cores or millicores such as 500m",
+	RuleRequestsExceedLimits:   "Resource requests must not exceed their limits",
+	RuleNonNegative:            "Value must not be negative",
+	RuleEnvName:                "Environment variable names must be C identifiers",
+	RuleEnvSource:              "Environment variables take either value or valueFrom, and envFrom items one of configMapRef and secretRef",
+	RuleVolumeNameUnique:       "Volume names must be unique within a pod",
+	RuleVolumeNotFound:         "Volume mounts must refer to a volume declared by the pod",
+	RuleAbsolutePath:           "Path must be absolute",
+	RuleDuplicateKey:           "Mapping keys must not be repeated",
+	RuleUnknownField:           "Field is not part of the Kubernetes schema",
+	RuleLabelFormat:            "Label keys must be qualified names and values at most 63 alphanumeric characters",
+	RuleAnnotationKey:          "Annotation keys must be qualified names",
+	RuleImageLatest:            "Image must not use the latest tag or another forbidden tag",
+	RuleProbeHandler:           "Probes must specify exactly one of httpGet, tcpSocket and exec",
+	RuleNameFormat:             "Names must be valid DNS-1123 labels or subdomains",
+	RuleMemoryZero:             "Memory quantities must be greater than 0",
+	RuleHostPort:               "With hostNetwork, hostPort must equal containerPort",
+	RuleTolerationValue:        "Tolerations with operator Exists must not set a value",
+	RuleIPAddress:              "IP addresses must be valid IPv4 or IPv6 addresses",
+	RuleDeprecatedField:        "Field is deprecated in favour of another",
+	RulePortName:               "Port names must be IANA service names of at most 15 characters",
+	RulePortNameUnique:         "Port names must be unique within a container",
+	RuleHostPortUnique:         "Host ports, and container ports under hostNetwork, must be unique within a pod for each host IP and protocol",
+	RuleImageDigest:            "Image digests must be sha256 followed by 64 hex digits",
+	RuleVolumeSource:           "Volumes must specify exactly one volume source",
+	RulePriorityClass:          "Pod priority should come from a priorityClassName",
+	RuleMountPropagation:       "Bidirectional mount propagation requires a privileged container",
+	RuleSubPath:                "Volume mounts take at most one of subPath and subPathExpr, which must stay within the volume",
+	RuleSelectorLabels:         "Deployment selector matchLabels must be present in the pod template labels",
+	RulePortNotFound:           "Named probe and hook ports must refer to a port declared by the container",
+	RuleMemoryGranularity:      "Memory quantities must be a multiple of the configured granularity",
+	RuleLegacyBool:             "Boolean fields should not hold YAML 1.1 booleans such as yes or off, which are strings in YAML 1.2",
+	RulePortProtocol:           "Ports should state their protocol instead of relying on the TCP default",
+	RuleServerManaged:          "Fields managed by the API server should not be set in manifests",
+	RuleNameLength:             "Names must not exceed the length Kubernetes allows for them",
+	RuleStdinOnce:              "Containers may only set stdinOnce when stdin is true",
+	RuleContainerRestartPolicy: "Only init containers may set restartPolicy, and only to Always to run as a sidecar",
+	RuleSelectorOperator:       "Selector operators In and NotIn require values, Exists and DoesNotExist must not have any",
+}
+
+// Describe returns a one-line description of the check identified by rule.
+func Describe(rule string) string {
+	return ruleDescriptions[rule]
+}
+
+var ruleHints = map[string]string{
+	RuleSyntax:                 "check indentation and quoting around the reported line",
+	RuleEmptyDocument:          "add a Pod or Deployment manifest, or remove the file",
+	RuleRequired:               "add the missing field",
+	RuleType:                   "change the value to the expected type; quote strings that look like numbers or booleans",
+	RuleUnsupportedValue:       "use one of the values Kubernetes accepts for this field",
+	RuleNotEmpty:               "add at least one item",
+	RuleContainerName:          "use lowercase letters, digits and underscores, starting with a letter",
+	RuleContainerNameUnique:    "rename one of the containers",
+	RuleImageFormat:            "write the image as registry/repository:tag, with the repository in lowercase",
+	RuleImageRegistry:          "push the image to an allowed registry and reference it from there",
+	RuleImageTag:               "append a tag such as :1.2.3 to the image, using letters, digits, '_', '.' and '-'",
+	RulePortRange:              "use a port between 1 and 65535",
+	RuleProbePath:              "use an absolute path such as /healthz, without query or fragment",
+	RuleResourceName:           "check the spelling, or allow the extended resource with -extended-resources",
+	RuleMemoryFormat:           "write memory as an integer with a unit, such as 512Mi or 1Gi",
+	RuleCPUFormat:              "write cpu as whole cores such as 2 or millicores such as 500m",
+	RuleRequestsExceedLimits:   "lower the request or raise the limit",
+	RuleNonNegative:            "use 0 or a positive number",
+	RuleEnvName:                "use letters, digits and underscores, not starting with a digit",
+	RuleEnvSource:              "keep exactly one source and remove the others",
+	RuleVolumeNameUnique:       "rename one of the volumes",
+	RuleVolumeNotFound:         "declare the volume in spec.volumes or fix the name of the mount",
+	RuleAbsolutePath:           "start the path with /",
+	RuleDuplicateKey:           "remove or merge the repeated key; only one of the values would be used",
+	RuleUnknownField:           "check the spelling of the field or remove it",
+	RuleLabelFormat:            "use up to 63 letters, digits, '-', '_' and '.', starting and ending with a letter or digit",
+	RuleAnnotationKey:          "use a name such as example.com/owner",
+	RuleImageLatest:            "pin the image to a specific version tag",
+	RuleProbeHandler:           "keep exactly one of httpGet, tcpSocket and exec",
+	RuleNameFormat:             "use lowercase letters, digits, '-' and '.', starting and ending with a letter or digit",
+	RuleMemoryZero:             "set a positive amount of memory or remove the field",
+	RuleHostPort:               "set hostPort to the same value as containerPort, or remove it",
+	RuleTolerationValue:        "remove value or change operator to Equal",
+	RuleIPAddress:              "use an IPv4 address such as 10.0.0.1 or an IPv6 address such as ::1",
+	RuleDeprecatedField:        "rename the field as suggested",
+	RulePortName:               "use up to 15 lowercase letters, digits and single hyphens, with at least one letter",
+	RulePortNameUnique:         "rename one of the ports",
+	RuleHostPortUnique:         "use a different hostPort, hostIP or protocol",
+	RuleImageDigest:            "reference the image as name@sha256:<64 hex digits>",
+	RuleVolumeSource:           "keep exactly one source such as emptyDir, configMap or persistentVolumeClaim",
+	RulePriorityClass:          "set priorityClassName and let the scheduler derive priority from it",
+	RuleMountPropagation:       "set securityContext.privileged to true or use HostToContainer",
+	RuleSubPath:                "keep one of subPath and subPathExpr and remove '..' from the path",
+	RuleSelectorLabels:         "add the label to spec.template.metadata.labels or remove it from the selector",
+	RulePortNotFound:           "declare the port in the container's ports with this name or use a port number",
+	RuleMemoryGranularity:      "round the quantity to a multiple of the granularity",
+	RuleLegacyBool:             "write true or false instead",
+	RulePortProtocol:           "add protocol: TCP, UDP or SCTP",
+	RuleServerManaged:          "remove the field; it was probably copied from kubectl get -o yaml",
+	RuleNameLength:             "shorten the name",
+	RuleStdinOnce:              "set stdin: true or remove stdinOnce",
+	RuleContainerRestartPolicy: "remove restartPolicy, or set it to Always on an init container",
+	RuleSelectorOperator:       "add values for In and NotIn, or remove them for Exists and DoesNotExist",
+}
+
+// Explain returns a short suggestion for fixing errors of rule, or an empty
+// string if there is none.
+func Explain(rule string) string {
+	return ruleHints[rule]
+}
+
+// Describe is like the package-level Describe but describes the checks as
+// configured by o, naming a custom NamePattern, for instance.
+func (o Options) Describe(rule string) string {
+	if rule == RuleContainerName && o.NamePattern != nil {
+		return "Container name must match " + o.NamePattern.String()
+	}
+	return Describe(rule)
+}
+
+// Explain is like the package-level Explain but suggests fixes for the
+// checks as configured by o.
+func (o Options) Explain(rule string) string {
+	if rule == RuleContainerName && o.NamePattern != nil {
+		return "rename the container to match " + o.NamePattern.String()
+	}
+	return Explain(rule)
+}
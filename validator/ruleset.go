@@ -0,0 +1,83 @@
+package validator
+
+import "gopkg.in/yaml.v3"
+
+// Rule is a check that runs on every document after the built-in checks.
+// doc is the root mapping of the document with aliases and merge keys
+// resolved, and file is the filename passed to Check. Errors without a
+// Filename or Severity are reported as errors in file. Rules may share the
+// rule identifiers of this package or use their own, for which Describe
+// and Explain return empty strings.
+type Rule interface {
+	Check(doc *yaml.Node, file string) []*ValidationError
+}
+
+// RuleFunc adapts an ordinary function to a Rule.
+type RuleFunc func(doc *yaml.Node, file string) []*ValidationError
+
+// Check calls f(doc, file).
+func (f RuleFunc) Check(doc *yaml.Node, file string) []*ValidationError {
+	return f(doc, file)
+}
+
+// RuleSet is an ordered list of rules. Rules must be registered before the
+// set is used; a set that is no longer changed may be used by several
+// goroutines at once.
+type RuleSet struct {
+	rules []Rule
+}
+
+// Register appends rule to s.
+func (s *RuleSet) Register(rule Rule) {
+	s.rules = append(s.rules, rule)
+}
+
+// Check runs every rule of s on doc in the order they were registered and
+// returns their errors.
+func (s *RuleSet) Check(doc *yaml.Node, file string) []*ValidationError {
+	var errs []*ValidationError
+	for _, rule := range s.rules {
+		errs = append(errs, rule.Check(doc, file)...)
+	}
+	return errs
+}
+
+// defaultRules holds the rules added with Register.
+var defaultRules RuleSet
+
+// Register adds rule to the rules Check runs unless Options.Rules is set.
+// It is meant to be called from an init function of a program embedding
+// the validator, such as a copy of the yamlvalid command with
+// organization-specific checks.
+func Register(rule Rule) {
+	defaultRules.Register(rule)
+}
+
+// Builtin returns the built-in checks, configured by opts, as a Rule. Check
+// always runs them before any other rules; Builtin lets them be combined
+// with custom rules in a RuleSet of their own.
+func Builtin(opts Options) Rule {
+	return RuleFunc(func(doc *yaml.Node, file string) []*ValidationError {
+		v := &podValidator{Options: opts, filename: file}
+		v.validateDocument(doc)
+		return v.result().Errors
+	})
+}
+
+// checkRules runs the custom rules on doc, which has been validated by the
+// built-in checks already.
+func (v *podValidator) checkRules(doc *yaml.Node) {
+	rules := v.Rules
+	if rules == nil {
+		rules = &defaultRules
+	}
+	for _, e := range rules.Check(doc, v.filename) {
+		if e.Filename == "" {
+			e.Filename = v.filename
+		}
+		if e.Severity == "" {
+			e.Severity = SeverityError
+		}
+		v.errs = append(v.errs, e)
+	}
+}
@@ -0,0 +1,269 @@
+package validator
+
+import (
+	"regexp"
+	"sort"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// object is a JSON Schema fragment.
+type object = map[string]any
+
+// Schema returns a JSON Schema describing the Pod manifests accepted by
+// Validate with default options. Patterns and allowed values are taken from
+// the same tables the validator uses, so the two stay in sync. Checks that
+// span several fields, such as requests not exceeding limits, cannot be
+// expressed and are left out.
+func Schema() map[string]any {
+	return object{
+		"$schema":     jsonSchemaDraft,
+		"title":       "Pod",
+		"description": "Kubernetes Pod manifest as checked by yamlvalid",
+		"type":        "object",
+		"required":    []string{"apiVersion", "kind", "metadata", "spec"},
+		"properties": object{
+			"apiVersion": object{"enum": apiVersions["Pod"]},
+			"kind":       object{"const": "Pod"},
+			"metadata":   objectMetaSchema(),
+			"spec":       podSpecSchema(),
+		},
+	}
+}
+
+func objectMetaSchema() object {
+	return object{
+		"type":     "object",
+		"required": []string{"name"},
+		"properties": object{
+			"name":        object{"type": "string", "maxLength": maxSubdomainLength, "pattern": dnsSubdomainRegex.String()},
+			"namespace":   object{"type": "string", "maxLength": maxLabelLength, "pattern": dnsLabelRegex.String()},
+			"labels":      stringMapSchema(),
+			"annotations": stringMapSchema(),
+		},
+	}
+}
+
+func podSpecSchema() object {
+	return object{
+		"type":     "object",
+		"required": []string{"containers"},
+		"properties": object{
+			"os": object{
+				"type":       "object",
+				"required":   []string{"name"},
+				"properties": object{"name": enumSchema(validOSNames)},
+			},
+			"restartPolicy":      enumSchema(validRestarts),
+			"hostNetwork":        object{"type": "boolean"},
+			"hostPID":            object{"type": "boolean"},
+			"hostIPC":            object{"type": "boolean"},
+			"serviceAccountName": object{"type": "string", "maxLength": maxSubdomainLength, "pattern": dnsSubdomainRegex.String()},
+			"nodeSelector":       stringMapSchema(),
+			"priorityClassName":  object{"type": "string"},
+			"priority":           object{"type": "integer"},
+			"tolerations": arraySchema(object{
+				"type": "object",
+				"properties": object{
+					"key":               object{"type": "string"},
+					"value":             object{"type": "string"},
+					"operator":          enumSchema(validOperators),
+					"effect":            enumSchema(validEffects),
+					"tolerationSeconds": object{"type": "integer"},
+				},
+			}),
+			"hostAliases": arraySchema(object{
+				"type":     "object",
+				"required": []string{"ip"},
+				"properties": object{
+					"ip":        object{"type": "string", "anyOf": []object{{"format": "ipv4"}, {"format": "ipv6"}}},
+					"hostnames": arraySchema(object{"type": "string", "minLength": 1}),
+				},
+			}),
+			"securityContext": securityContextSchema(),
+			"volumes": arraySchema(object{
+				"type":       "object",
+				"required":   []string{"name"},
+				"properties": object{"name": object{"type": "string", "minLength": 1}},
+			}),
+			"containers":     object{"type": "array", "minItems": 1, "items": containerSchema()},
+			"initContainers": arraySchema(initContainerSchema()),
+		},
+	}
+}
+
+// initContainerSchema extends containerSchema with the restartPolicy that
+// turns an init container into a sidecar.
+func initContainerSchema() object {
+	schema := containerSchema()
+	schema["properties"].(object)["restartPolicy"] = object{"const": "Always"}
+	return schema
+}
+
+func containerSchema() object {
+	return object{
+		"type":     "object",
+		"required": []string{"name", "image", "resources"},
+		"properties": object{
+			"name":            object{"type": "string", "maxLength": maxLabelLength, "pattern": snakeCaseRegex.String()},
+			"image":           object{"type": "string", "pattern": "^" + regexp.QuoteMeta(domainRequired) + "/.+(:\\w[\\w.-]{0,127}|@sha256:[0-9a-f]{64})$"},
+			"imagePullPolicy": enumSchema(validPullPolicies),
+			"command":         arraySchema(object{"type": "string"}),
+			"args":            arraySchema(object{"type": "string"}),
+			"stdin":           object{"type": "boolean"},
+			"stdinOnce":       object{"type": "boolean"},
+			"tty":             object{"type": "boolean"},
+			"ports": arraySchema(object{
+				"type":     "object",
+				"required": []string{"containerPort"},
+				"properties": object{
+					"containerPort": portSchema(),
+					"protocol":      enumSchema(validProtocols),
+				},
+			}),
+			"env": arraySchema(object{
+				"type":     "object",
+				"required": []string{"name"},
+				"properties": object{
+					"name":      object{"type": "string", "pattern": envVarNameRegex.String()},
+					"value":     object{"type": "string"},
+					"valueFrom": object{"type": "object"},
+				},
+				"not": object{"required": []string{"value", "valueFrom"}},
+			}),
+			"volumeMounts": arraySchema(object{
+				"type":     "object",
+				"required": []string{"name", "mountPath"},
+				"not":      object{"required": []string{"subPath", "subPathExpr"}},
+				"properties": object{
+					"name":             object{"type": "string", "minLength": 1},
+					"mountPath":        object{"type": "string", "pattern": "^/"},
+					"readOnly":         object{"type": "boolean"},
+					"subPath":          object{"type": "string"},
+					"subPathExpr":      object{"type": "string"},
+					"mountPropagation": enumSchema(validPropagations),
+				},
+			}),
+			"securityContext": securityContextSchema(),
+			"readinessProbe":  handlerSchema(),
+			"livenessProbe":   handlerSchema(),
+			"startupProbe":    handlerSchema(),
+			"lifecycle": object{
+				"type": "object",
+				"properties": object{
+					"postStart": handlerSchema(),
+					"preStop":   handlerSchema(),
+				},
+			},
+			"resources": object{
+				"type": "object",
+				"properties": object{
+					"requests": resourceListSchema(),
+					"limits":   resourceListSchema(),
+				},
+			},
+		},
+	}
+}
+
+func securityContextSchema() object {
+	return object{
+		"type": "object",
+		"properties": object{
+			"runAsUser":                object{"type": "integer"},
+			"runAsGroup":               object{"type": "integer"},
+			"runAsNonRoot":             object{"type": "boolean"},
+			"readOnlyRootFilesystem":   object{"type": "boolean"},
+			"privileged":               object{"type": "boolean"},
+			"allowPrivilegeEscalation": object{"type": "boolean"},
+		},
+	}
+}
+
+// handlerSchema describes a probe or lifecycle hook, which must specify
+// exactly one handler.
+func handlerSchema() object {
+	oneOf := make([]object, 0, len(probeHandlers))
+	for _, handler := range probeHandlers {
+		oneOf = append(oneOf, object{"required": []string{handler}})
+	}
+	return object{
+		"type":  "object",
+		"oneOf": oneOf,
+		"properties": object{
+			"httpGet": object{
+				"type":     "object",
+				"required": []string{"path", "port"},
+				"properties": object{
+					"path":   object{"type": "string", "pattern": `^/([^/?#\s][^?#\s]*)?$`},
+					"port":   namedPortSchema(),
+					"scheme": enumSchema(validSchemes),
+					"httpHeaders": arraySchema(object{
+						"type":     "object",
+						"required": []string{"name", "value"},
+						"properties": object{
+							"name":  object{"type": "string"},
+							"value": object{"type": "string"},
+						},
+					}),
+				},
+			},
+			"tcpSocket": object{
+				"type":       "object",
+				"required":   []string{"port"},
+				"properties": object{"port": namedPortSchema()},
+			},
+			"exec": object{
+				"type":       "object",
+				"properties": object{"command": object{"type": "array", "minItems": 1, "items": object{"type": "string"}}},
+			},
+		},
+	}
+}
+
+func resourceListSchema() object {
+	return object{
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties": object{
+			"cpu": object{"anyOf": []object{
+				{"type": "integer", "minimum": 0},
+				{"type": "string", "pattern": millicoresRegex.String()},
+			}},
+			"memory":            object{"type": "string", "pattern": memoryUnitRegex.String()},
+			"ephemeral-storage": object{"type": "string", "pattern": memoryUnitRegex.String()},
+		},
+	}
+}
+
+func portSchema() object {
+	return object{"type": "integer", "minimum": minPort, "maximum": maxPort}
+}
+
+// namedPortSchema describes a port given either as a number or as the name
+// of a container port.
+func namedPortSchema() object {
+	return object{"anyOf": []object{
+		portSchema(),
+		{"type": "string", "maxLength": maxPortNameLength, "pattern": portNameRegex.String()},
+	}}
+}
+
+func stringMapSchema() object {
+	return object{"type": "object", "additionalProperties": object{"type": "string"}}
+}
+
+func arraySchema(items object) object {
+	return object{"type": "array", "items": items}
+}
+
+// enumSchema returns a string schema allowing the keys of values, sorted so
+// that the output is stable.
+func enumSchema(values map[string]bool) object {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return object{"type": "string", "enum": keys}
+}
@@ -0,0 +1,2468 @@
+// Package validator checks Kubernetes Pod and Deployment manifests written in
+// YAML against the rules enforced by the yamlvalid command.
+package validator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	domainRequired = "registry.bigbrother.io"
+	memoryUnitList = "Ki, Mi, Gi, k, M, G"
+	protocolList   = "TCP, UDP, SCTP"
+	minPort        = 1
+	maxPort        = 65535
+)
+
+// Length limits Kubernetes puts on names, in characters.
+const (
+	// maxSubdomainLength applies to object names, service account names
+	// and the prefixes of qualified names.
+	maxSubdomainLength = 253
+	// maxLabelLength applies to namespaces, container names, label values
+	// and the name part of qualified names.
+	maxLabelLength = 63
+	// maxPortNameLength applies to container port names.
+	maxPortNameLength = 15
+)
+
+var (
+	// apiVersions lists the apiVersions accepted for each supported kind.
+	apiVersions = map[string][]string{
+		"Pod":        {"v1"},
+		"Deployment": {"apps/v1"},
+		"List":       {"v1"},
+	}
+	// serverManagedMetadata are the metadata fields the API server sets,
+	// which end up in manifests copied from kubectl get -o yaml.
+	serverManagedMetadata = []string{"creationTimestamp", "uid", "resourceVersion", "generation"}
+
+	validOSNames      = map[string]bool{"linux": true, "windows": true}
+	validProtocols    = map[string]bool{"TCP": true, "UDP": true, "SCTP": true}
+	validPullPolicies = map[string]bool{"Always": true, "Never": true, "IfNotPresent": true}
+	validRestarts     = map[string]bool{"Always": true, "OnFailure": true, "Never": true}
+	validSchemes      = map[string]bool{"HTTP": true, "HTTPS": true}
+
+	// selectorOperators maps the operators of label selector requirements
+	// to whether they take values.
+	selectorOperators = map[string]bool{"In": true, "NotIn": true, "Exists": false, "DoesNotExist": false}
+	// legacyBools are the YAML 1.1 booleans that YAML 1.2 reads as strings.
+	legacyBools       = map[string]bool{"yes": true, "no": true, "on": true, "off": true}
+	validMedia        = map[string]bool{"": true, "Memory": true}
+	validPropagations = map[string]bool{"None": true, "HostToContainer": true, "Bidirectional": true}
+	validDNSPolicies  = map[string]bool{"ClusterFirst": true, "ClusterFirstWithHostNet": true, "Default": true, "None": true}
+	validOperators    = map[string]bool{"Exists": true, "Equal": true}
+	validEffects      = map[string]bool{"NoSchedule": true, "PreferNoSchedule": true, "NoExecute": true}
+	probeHandlers     = []string{"httpGet", "tcpSocket", "exec"}
+	validResourceKeys = map[string]bool{"cpu": true, "memory": true, "ephemeral-storage": true}
+	memoryUnitRegex   = regexp.MustCompile(`^(\d+)(Gi|Mi|Ki|G|M|k)$`)
+	imageDigestRegex  = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+	imageTagRegex     = regexp.MustCompile(`^\w[\w.-]{0,127}$`)
+	millicoresRegex   = regexp.MustCompile(`^\d+m$`)
+	memoryUnits       = map[string]int64{
+		"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30,
+		"k": 1e3, "M": 1e6, "G": 1e9,
+	}
+	snakeCaseRegex    = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	envVarNameRegex   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	labelValueRegex   = regexp.MustCompile(`^[a-z0-9A-Z]([a-z0-9A-Z._-]*[a-z0-9A-Z])?$`)
+	portNameRegex     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	dnsLabelRegex     = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	dnsSubdomainRegex = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+
+	syntaxErrorLineRegex = regexp.MustCompile(`^line (\d+): (.*)$`)
+)
+
+// Severities of a ValidationError. Warnings point out likely mistakes in
+// manifests that Kubernetes would still accept.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// ValidationError describes a single problem found in a manifest. Line is 0
+// when the problem is not tied to a particular line, such as a missing field.
+type ValidationError struct {
+	Filename string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	msg := e.Message
+	if e.IsWarning() {
+		msg = "warning: " + msg
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%s %s", e.Filename, e.Position(), msg)
+	}
+	return fmt.Sprintf("%s %s", e.Filename, msg)
+}
+
+// Position returns the line of e followed by its column, if known, such as
+// "12:7".
+func (e *ValidationError) Position() string {
+	if e.Column > 0 {
+		return fmt.Sprintf("%d:%d", e.Line, e.Column)
+	}
+	return strconv.Itoa(e.Line)
+}
+
+// IsWarning reports whether e is a warning rather than an error.
+func (e *ValidationError) IsWarning() bool {
+	return e.Severity == SeverityWarning
+}
+
+// Validate validates every document of a (possibly multi-document) YAML
+// stream and returns the errors in the order they were found. filename is
+// only used to label the errors. Documents are validated independently, so
+// an invalid document does not hide problems in the ones after it. A syntax
+// error ends validation but is reported after the errors found in the
+// documents before it.
+func Validate(content []byte, filename string, opts Options) []*ValidationError {
+	return Check(content, filename, opts).Errors
+}
+
+// Result is the outcome of validating a YAML stream.
+type Result struct {
+	// Errors holds the errors and warnings in the order they were found.
+	Errors []*ValidationError
+	// Containers counts the containers and init containers validated.
+	Containers int
+}
+
+// Check is like Validate but also reports what was validated.
+func Check(content []byte, filename string, opts Options) *Result {
+	validator := &podValidator{
+		Options:  opts,
+		filename: filename,
+		content:  content,
+	}
+
+	// Catch blank input before decoding so that it is not confused with a
+	// file holding only comments, which decodes the same way.
+	if len(bytes.TrimSpace(content)) == 0 {
+		validator.addError(RuleEmptyDocument, nil, "file is empty")
+		return validator.result()
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	docs := 0
+	for {
+		var root yaml.Node
+		if err := decoder.Decode(&root); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			line, msg := splitSyntaxError(err)
+			if at := validator.syntaxErrorLine(err, line); at > 0 {
+				line = at
+			}
+			// yaml.v3 reports tabs in indentation in terms of the
+			// scanner, which rarely points at the actual problem. Tabs
+			// elsewhere, such as inside a block scalar, are legal and
+			// must not hide the real error.
+			if strings.Contains(msg, "tab character") || validator.tabIndented(line) {
+				validator.addSyntaxError(line, "found tab character in indentation; YAML requires spaces")
+				return validator.result()
+			}
+			validator.addSyntaxError(line, "cannot unmarshal YAML: "+msg)
+			return validator.result()
+		}
+		if len(root.Content) == 0 || isEmptyDocument(root.Content[0]) {
+			continue
+		}
+		docs++
+
+		doc := root.Content[0]
+		if doc.Kind != yaml.MappingNode {
+			validator.addError(RuleType, doc, "root must be a mapping")
+			continue
+		}
+		validator.validateDocument(doc)
+		validator.checkRules(resolveAliases(doc))
+	}
+
+	if docs == 0 {
+		validator.addError(RuleEmptyDocument, nil, "file contains no YAML documents")
+	}
+	return validator.result()
+}
+
+// result returns the errors found so far. A node reached through several
+// aliases is validated each time, so identical errors at the same position
+// are reported once.
+func (v *podValidator) result() *Result {
+	type key struct {
+		line, column  int
+		rule, message string
+	}
+	seen := make(map[key]bool)
+	errs := v.errs[:0]
+	for _, e := range v.errs {
+		k := key{e.Line, e.Column, e.Rule, e.Message}
+		if e.Line > 0 && seen[k] {
+			continue
+		}
+		seen[k] = true
+		errs = append(errs, e)
+	}
+	return &Result{Errors: errs, Containers: v.containers}
+}
+
+// tabIndented reports whether the indentation of the 1-based line of the
+// content contains a tab.
+func (v *podValidator) tabIndented(line int) bool {
+	lines := bytes.Split(v.content, []byte("\n"))
+	if line < 1 || line > len(lines) {
+		return false
+	}
+	text := lines[line-1]
+	indent := text[:len(text)-len(bytes.TrimLeft(text, " \t"))]
+	return bytes.IndexByte(indent, '\t') >= 0 && len(bytes.TrimSpace(text)) > 0
+}
+
+// syntaxErrorLine returns the number of the line the syntax error err is on,
+// or 0 if it cannot be found. yaml.v3 reports parser errors, such as an
+// unclosed bracket, at the start of the enclosing collection and counts
+// those lines from 0, so reported, the line in its message, can be off by
+// one or many; the problem is never before it, though. The content cut short
+// after any line from the problem on fails with err, so a binary search over
+// those lengths finds the line with a handful of decodes.
+func (v *podValidator) syntaxErrorLine(err error, reported int) int {
+	// ends[i] is the offset just past line i+1.
+	var ends []int
+	for i, c := range v.content {
+		if c == '\n' {
+			ends = append(ends, i+1)
+		}
+	}
+	if len(ends) == 0 || ends[len(ends)-1] < len(v.content) {
+		ends = append(ends, len(v.content))
+	}
+	first := max(reported, 1)
+	if first > len(ends) {
+		return 0
+	}
+	n := sort.Search(len(ends)-first+1, func(i int) bool {
+		e := decodeAll(v.content[:ends[first+i-1]])
+		return e != nil && e.Error() == err.Error()
+	})
+	if n > len(ends)-first {
+		return 0
+	}
+	return first + n
+}
+
+// decodeAll decodes every document of content and returns the first error.
+func decodeAll(content []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for {
+		var root yaml.Node
+		if err := decoder.Decode(&root); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// splitSyntaxError extracts the line number yaml.v3 embeds in the text of its
+// syntax errors ("yaml: line 4: did not find expected key"). The line is 0 if
+// the error does not mention one.
+func splitSyntaxError(err error) (int, string) {
+	msg := strings.TrimPrefix(err.Error(), "yaml: ")
+	m := syntaxErrorLineRegex.FindStringSubmatch(msg)
+	if m == nil {
+		return 0, msg
+	}
+	line, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0, msg
+	}
+	return line, m[2]
+}
+
+// isEmptyDocument reports whether node is the implicit null produced by a
+// document with no content, such as the one after a trailing "---".
+func isEmptyDocument(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null" && node.Value == ""
+}
+
+// Options control which checks the validators run.
+type Options struct {
+	// Strict additionally reports fields unknown to the Kubernetes API.
+	Strict bool
+	// Registries lists the registry prefixes images may come from, such as
+	// "registry.example.com" or "registry.example.com/team". When empty,
+	// only registry.bigbrother.io is allowed.
+	Registries []string
+	// NoLatest rejects images tagged "latest".
+	NoLatest bool
+	// ForbiddenTags lists further image tags to reject.
+	ForbiddenTags []string
+	// NamePattern, if set, replaces the snake_case pattern container names
+	// must match.
+	NamePattern *regexp.Regexp
+	// RequireDigest rejects images that are not pinned by a sha256 digest.
+	RequireDigest bool
+	// RequireRequests makes cpu and memory requests mandatory for every
+	// container.
+	RequireRequests bool
+	// MemoryGranularity, if set, is a memory quantity such as 128Mi that
+	// memory requests and limits must be a multiple of. It must be valid
+	// according to ParseMemory.
+	MemoryGranularity string
+	// ExtendedResources lists further resource names, such as
+	// nvidia.com/gpu, that containers may request as whole numbers.
+	ExtendedResources []string
+	// Logger, if set, receives a debug record for every object the
+	// validator descends into.
+	Logger *slog.Logger
+	// Rules, if set, replaces the rules added with Register as the custom
+	// rules run after the built-in checks.
+	Rules *RuleSet
+}
+
+// namePattern returns the pattern container names must match: NamePattern,
+// or snake_case if it is not set.
+func (o Options) namePattern() *regexp.Regexp {
+	if o.NamePattern != nil {
+		return o.NamePattern
+	}
+	return snakeCaseRegex
+}
+
+type podValidator struct {
+	Options
+	filename string
+	content  []byte
+	errs     []*ValidationError
+	// prefix is prepended to error messages, locating the object being
+	// validated within a List.
+	prefix     string
+	containers int
+}
+
+// addError records a validation error. Validation always continues past it;
+// whether the remaining errors are reported is up to the caller.
+func (v *podValidator) addError(rule string, node *yaml.Node, msg string) {
+	v.add(rule, SeverityError, node, msg)
+}
+
+// addWarning records a problem that does not make the manifest invalid on
+// its own.
+func (v *podValidator) addWarning(rule string, node *yaml.Node, msg string) {
+	v.add(rule, SeverityWarning, node, msg)
+}
+
+// add records a problem at the position of node. A nil node, used for
+// missing fields, has no position.
+func (v *podValidator) add(rule, severity string, node *yaml.Node, msg string) {
+	e := &ValidationError{Filename: v.filename, Rule: rule, Severity: severity, Message: v.prefix + msg}
+	if node != nil {
+		e.Line, e.Column = node.Line, node.Column
+	}
+	v.errs = append(v.errs, e)
+}
+
+// addSyntaxError records a syntax error, for which only the line is known.
+func (v *podValidator) addSyntaxError(line int, msg string) {
+	v.errs = append(v.errs, &ValidationError{Filename: v.filename, Line: line, Rule: RuleSyntax, Severity: SeverityError, Message: msg})
+}
+
+// validateDocument dispatches a top-level document to the validator for its
+// kind. Anything that is not a Deployment is validated as a Pod, which also
+// reports missing and unsupported kinds.
+func (v *podValidator) validateDocument(node *yaml.Node) {
+	v.checkDuplicateKeys(node)
+	v.validateObject(resolveAliases(node))
+}
+
+// validateObject validates a single manifest, or each manifest of a List.
+func (v *podValidator) validateObject(node *yaml.Node) {
+	fields := v.parseMapping(node)
+
+	if kind, ok := fields["kind"]; ok && kind.Value == "List" {
+		v.checkUnknownFields(node, "root", listFields)
+		v.validateList(fields)
+		return
+	}
+
+	v.checkUnknownFields(node, "root", topLevelFields)
+	if status, ok := fields["status"]; ok && v.Strict {
+		v.addWarning(RuleServerManaged, status, "status field should not be set in a manifest; it is managed by the API server")
+	}
+	if kind, ok := fields["kind"]; ok && kind.Value == "Deployment" {
+		v.validateDeployment(fields)
+		return
+	}
+	v.validatePod(fields)
+}
+
+// validateList validates each item of a kind: List document as a manifest
+// of its own. Errors in an item are prefixed with its position.
+func (v *podValidator) validateList(fields map[string]*yaml.Node) {
+	v.validateAPIVersion(fields)
+
+	items, ok := fields["items"]
+	if !ok {
+		v.addError(RuleRequired, nil, "items is required")
+		return
+	}
+	if items.Kind != yaml.SequenceNode {
+		v.addError(RuleType, items, "items must be a sequence")
+		return
+	}
+
+	prefix := v.prefix
+	defer func() { v.prefix = prefix }()
+	for i, item := range items.Content {
+		v.prefix = prefix
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, fmt.Sprintf("items[%d] must be a mapping", i))
+			continue
+		}
+		v.prefix = fmt.Sprintf("%sitems[%d].", prefix, i)
+		v.validateObject(item)
+	}
+}
+
+// validateAPIVersion checks that apiVersion is one of those accepted for the
+// kind of the object. Objects without a supported kind are only required to
+// have an apiVersion, as the kind itself is reported.
+func (v *podValidator) validateAPIVersion(fields map[string]*yaml.Node) {
+	apiVersion, ok := fields["apiVersion"]
+	if !ok {
+		v.addError(RuleRequired, nil, "apiVersion is required")
+		return
+	}
+	kind, ok := fields["kind"]
+	if !ok {
+		return
+	}
+	versions, ok := apiVersions[kind.Value]
+	if !ok {
+		return
+	}
+	for _, version := range versions {
+		if apiVersion.Value == version {
+			return
+		}
+	}
+	v.addError(RuleUnsupportedValue, apiVersion, "apiVersion '"+apiVersion.Value+"' is not supported for kind "+kind.Value)
+}
+
+func (v *podValidator) validatePod(fields map[string]*yaml.Node) {
+	v.validateAPIVersion(fields)
+
+	if kind, ok := fields["kind"]; !ok {
+		v.addError(RuleRequired, nil, "kind is required")
+	} else if kind.Value != "Pod" {
+		v.addError(RuleUnsupportedValue, kind, "kind has unsupported value '"+kind.Value+"'")
+	}
+
+	if metadata, ok := fields["metadata"]; !ok {
+		v.addError(RuleRequired, nil, "metadata is required")
+	} else {
+		v.validateObjectMeta(metadata, "metadata", true)
+	}
+
+	if spec, ok := fields["spec"]; !ok {
+		v.addError(RuleRequired, nil, "spec is required")
+	} else {
+		v.validatePodSpec(spec, "spec")
+	}
+}
+
+func (v *podValidator) validateDeployment(fields map[string]*yaml.Node) {
+	v.validateAPIVersion(fields)
+
+	if metadata, ok := fields["metadata"]; !ok {
+		v.addError(RuleRequired, nil, "metadata is required")
+	} else {
+		v.validateObjectMeta(metadata, "metadata", true)
+	}
+
+	if spec, ok := fields["spec"]; !ok {
+		v.addError(RuleRequired, nil, "spec is required")
+	} else {
+		v.validateDeploymentSpec(spec)
+	}
+}
+
+func (v *podValidator) validateDeploymentSpec(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, "spec must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, "spec", deploymentSpecFields)
+	fields := v.parseMapping(node)
+
+	if replicas, ok := fields["replicas"]; ok {
+		if n, err := v.parseInt(replicas); err != nil {
+			v.addError(RuleType, replicas, "spec.replicas must be int")
+		} else if n < 0 {
+			v.addError(RuleNonNegative, replicas, "spec.replicas must not be negative")
+		}
+	}
+
+	var matchLabels *yaml.Node
+	if selector, ok := fields["selector"]; !ok {
+		v.addError(RuleRequired, nil, "spec.selector is required")
+	} else if selector.Kind != yaml.MappingNode {
+		v.addError(RuleType, selector, "spec.selector must be a mapping")
+	} else {
+		v.checkUnknownFields(selector, "spec.selector", labelSelectorFields)
+		selectorFields := v.parseMapping(selector)
+		if matchLabels, ok = selectorFields["matchLabels"]; ok {
+			v.validateLabels(matchLabels, "spec.selector.matchLabels")
+		}
+		if expressions, ok := selectorFields["matchExpressions"]; ok {
+			v.validateMatchExpressions(expressions, "spec.selector.matchExpressions")
+		}
+	}
+
+	template, ok := fields["template"]
+	if !ok {
+		v.addError(RuleRequired, nil, "spec.template is required")
+		return
+	}
+	if template.Kind != yaml.MappingNode {
+		v.addError(RuleType, template, "spec.template must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(template, "spec.template", podTemplateFields)
+	templateFields := v.parseMapping(template)
+	var labels *yaml.Node
+	if metadata, ok := templateFields["metadata"]; ok {
+		v.validateObjectMeta(metadata, "spec.template.metadata", false)
+		if metadata.Kind == yaml.MappingNode {
+			labels = v.parseMapping(metadata)["labels"]
+		}
+	}
+	if matchLabels != nil && matchLabels.Kind == yaml.MappingNode {
+		v.validateSelectorLabels(matchLabels, labels)
+	}
+	if spec, ok := templateFields["spec"]; !ok {
+		v.addError(RuleRequired, nil, "spec.template.spec is required")
+	} else {
+		v.validatePodSpec(spec, "spec.template.spec")
+	}
+}
+
+// validateLabelSelector validates a label selector such as the labelSelector
+// of a pod affinity term.
+func (v *podValidator) validateLabelSelector(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, labelSelectorFields)
+	fields := v.parseMapping(node)
+
+	if matchLabels, ok := fields["matchLabels"]; ok {
+		v.validateLabels(matchLabels, path+".matchLabels")
+	}
+	if expressions, ok := fields["matchExpressions"]; ok {
+		v.validateMatchExpressions(expressions, path+".matchExpressions")
+	}
+}
+
+func (v *podValidator) validateMatchExpressions(node *yaml.Node, path string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, path+" must be a sequence")
+		return
+	}
+	for i, item := range node.Content {
+		v.validateLabelSelectorRequirement(item, fmt.Sprintf("%s[%d]", path, i))
+	}
+}
+
+// validateLabelSelectorRequirement validates one of the matchExpressions of
+// a label selector. In and NotIn need at least one value to compare the
+// label with, while Exists and DoesNotExist only look at the key and must
+// not have values.
+func (v *podValidator) validateLabelSelectorRequirement(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, labelSelectorRequirementFields)
+	fields := v.parseMapping(node)
+
+	if key, ok := fields["key"]; !ok {
+		v.addError(RuleRequired, nil, path+".key is required")
+	} else if key.Kind != yaml.ScalarNode || key.Value == "" {
+		v.addError(RuleType, key, path+".key must be string")
+	} else if msg := qualifiedNameLength(key.Value); msg != "" {
+		v.addError(RuleNameLength, key, path+".key '"+key.Value+"' "+msg)
+	} else if !isQualifiedName(key.Value) {
+		v.addError(RuleLabelFormat, key, path+".key '"+key.Value+"' has invalid format")
+	}
+
+	values, hasValues := fields["values"]
+	if hasValues {
+		v.validateStringList(values, path+".values")
+	}
+
+	operator, ok := fields["operator"]
+	if !ok {
+		v.addError(RuleRequired, nil, path+".operator is required")
+		return
+	}
+	if operator.Kind != yaml.ScalarNode {
+		v.addError(RuleType, operator, path+".operator must be string")
+		return
+	}
+	takesValues, ok := selectorOperators[operator.Value]
+	if !ok {
+		v.addError(RuleUnsupportedValue, operator, path+".operator has unsupported value '"+operator.Value+"'")
+		return
+	}
+	empty := !hasValues || (values.Kind == yaml.SequenceNode && len(values.Content) == 0)
+	if takesValues && empty {
+		v.addError(RuleSelectorOperator, operator, path+": operator "+operator.Value+" requires values")
+	} else if !takesValues && hasValues {
+		v.addError(RuleSelectorOperator, values, path+": operator "+operator.Value+" must not have values")
+	}
+}
+
+// validateSelectorLabels checks that every label in matchLabels is also set,
+// with the same value, in the pod template labels. Otherwise the Deployment
+// would not select the pods it creates.
+func (v *podValidator) validateSelectorLabels(matchLabels, labels *yaml.Node) {
+	var templateLabels map[string]*yaml.Node
+	if labels != nil && labels.Kind == yaml.MappingNode {
+		templateLabels = v.parseMapping(labels)
+	}
+	for i := 0; i+1 < len(matchLabels.Content); i += 2 {
+		key, value := matchLabels.Content[i], matchLabels.Content[i+1]
+		if label, ok := templateLabels[key.Value]; ok && label.Value == value.Value {
+			continue
+		}
+		v.addError(RuleSelectorLabels, key, "spec.selector.matchLabels '"+key.Value+"="+value.Value+"' not present in spec.template.metadata.labels")
+	}
+}
+
+// validateObjectMeta validates the metadata found at path. Pod templates
+// inherit their name from the owning object, so requireName is false there.
+func (v *podValidator) validateObjectMeta(node *yaml.Node, path string, requireName bool) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, objectMetaFields)
+	fields := v.parseMapping(node)
+
+	if name, ok := fields["name"]; !ok {
+		if requireName {
+			v.addError(RuleRequired, nil, path+".name is required")
+		}
+	} else if name.Kind != yaml.ScalarNode || name.Value == "" {
+		v.addError(RuleType, name, path+".name must be string")
+	} else if len(name.Value) > maxSubdomainLength {
+		v.addError(RuleNameLength, name, fmt.Sprintf("%s exceeds %d characters", path+".name", maxSubdomainLength))
+	} else if !isDNSSubdomain(name.Value) {
+		v.addError(RuleNameFormat, name, path+".name has invalid format '"+name.Value+"'")
+	}
+
+	if ns, ok := fields["namespace"]; ok {
+		if ns.Kind != yaml.ScalarNode || ns.Value == "" {
+			v.addError(RuleType, ns, path+".namespace must be string")
+		} else if len(ns.Value) > maxLabelLength {
+			v.addError(RuleNameLength, ns, fmt.Sprintf("%s exceeds %d characters", path+".namespace", maxLabelLength))
+		} else if !isDNSLabel(ns.Value) {
+			v.addError(RuleNameFormat, ns, path+".namespace has invalid format '"+ns.Value+"'")
+		}
+	}
+
+	if labels, ok := fields["labels"]; ok {
+		v.validateLabels(labels, path+".labels")
+	}
+
+	if annotations, ok := fields["annotations"]; ok {
+		v.validateAnnotations(annotations, path+".annotations")
+	}
+
+	if v.Strict {
+		for _, key := range serverManagedMetadata {
+			if value, ok := fields[key]; ok {
+				v.addWarning(RuleServerManaged, value, path+"."+key+" is managed by the API server; remove it from the manifest")
+			}
+		}
+	}
+}
+
+// validateStringList checks that node is a sequence of strings. Only
+// scalars explicitly typed as strings qualify, so 8080 or true do not.
+func (v *podValidator) validateStringList(node *yaml.Node, field string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, field+" must be a sequence")
+		return
+	}
+	for i, item := range node.Content {
+		if item.Kind != yaml.ScalarNode || item.Tag != "!!str" {
+			v.addError(RuleType, item, fmt.Sprintf("%s[%d] must be string", field, i))
+		}
+	}
+}
+
+// validateLabels checks that node is a mapping of label keys to label values
+// in the syntax Kubernetes accepts.
+func (v *podValidator) validateLabels(node *yaml.Node, field string) {
+	if !v.validateStringMap(node, field) {
+		return
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if msg := qualifiedNameLength(key.Value); msg != "" {
+			v.addError(RuleNameLength, key, field+" key '"+key.Value+"' "+msg)
+		} else if !isQualifiedName(key.Value) {
+			v.addError(RuleLabelFormat, key, field+" key '"+key.Value+"' has invalid format")
+		}
+		if len(value.Value) > maxLabelLength {
+			v.addError(RuleNameLength, value, fmt.Sprintf("%s value '%s' exceeds %d characters", field, value.Value, maxLabelLength))
+		} else if value.Value != "" && !labelValueRegex.MatchString(value.Value) {
+			v.addError(RuleLabelFormat, value, field+" value '"+value.Value+"' has invalid format")
+		}
+	}
+}
+
+// validateAnnotations checks that node is a mapping of qualified names to
+// strings. Unlike label values, annotation values are not restricted.
+func (v *podValidator) validateAnnotations(node *yaml.Node, field string) {
+	if !v.validateStringMap(node, field) {
+		return
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if msg := qualifiedNameLength(key.Value); msg != "" {
+			v.addError(RuleNameLength, key, field+" key '"+key.Value+"' "+msg)
+		} else if !isQualifiedName(key.Value) {
+			v.addError(RuleAnnotationKey, key, field+" key '"+key.Value+"' has invalid format")
+		}
+	}
+}
+
+// isDNSLabel reports whether s is a DNS-1123 label, as required for
+// namespace names.
+func isDNSLabel(s string) bool {
+	return len(s) <= maxLabelLength && dnsLabelRegex.MatchString(s)
+}
+
+// isDNSSubdomain reports whether s is a DNS-1123 subdomain, as required for
+// most object names.
+func isDNSSubdomain(s string) bool {
+	return len(s) <= maxSubdomainLength && dnsSubdomainRegex.MatchString(s)
+}
+
+// isQualifiedName reports whether s is a Kubernetes qualified name: a name of
+// at most 63 characters, optionally prefixed by a DNS subdomain and a slash,
+// as in "app.kubernetes.io/name".
+func isQualifiedName(s string) bool {
+	name := s
+	if prefix, rest, found := strings.Cut(s, "/"); found {
+		if !isDNSSubdomain(prefix) {
+			return false
+		}
+		name = rest
+	}
+	return len(name) <= maxLabelLength && labelValueRegex.MatchString(name)
+}
+
+// qualifiedNameLength describes how the prefix or name of the qualified name
+// s exceeds its length limit, or returns an empty string if neither does.
+func qualifiedNameLength(s string) string {
+	prefix, name, found := strings.Cut(s, "/")
+	if !found {
+		prefix, name = "", s
+	}
+	if len(prefix) > maxSubdomainLength {
+		return fmt.Sprintf("prefix exceeds %d characters", maxSubdomainLength)
+	}
+	if len(name) > maxLabelLength {
+		return fmt.Sprintf("name exceeds %d characters", maxLabelLength)
+	}
+	return ""
+}
+
+// validateStringMap checks that node is a mapping of strings to strings.
+// It reports whether that is the case. Unquoted numbers and booleans are
+// not strings, so values such as 8080 or true are rejected.
+func (v *podValidator) validateStringMap(node *yaml.Node, field string) bool {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, field+" must be a mapping")
+		return false
+	}
+	valid := true
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			v.addError(RuleType, key, field+" keys and values must be strings")
+			valid = false
+		}
+		if value.Kind != yaml.ScalarNode || value.Tag != "!!str" {
+			v.addError(RuleType, value, field+" keys and values must be strings")
+			valid = false
+		}
+	}
+	return valid
+}
+
+func (v *podValidator) validatePodSpec(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, podSpecFields)
+	fields := v.parseMapping(node)
+
+	if osNode, ok := fields["os"]; ok {
+		if osNode.Kind != yaml.MappingNode {
+			v.addError(RuleType, osNode, path+".os must be a mapping")
+		} else {
+			v.validatePodOS(osNode, path+".os")
+		}
+	}
+
+	if policy, ok := fields["restartPolicy"]; ok {
+		if policy.Kind != yaml.ScalarNode {
+			v.addError(RuleType, policy, path+".restartPolicy must be string")
+		} else if !validRestarts[policy.Value] {
+			v.addError(RuleUnsupportedValue, policy, path+".restartPolicy has unsupported value '"+policy.Value+"'")
+		}
+	}
+
+	if sc, ok := fields["securityContext"]; ok {
+		v.validateSecurityContext(sc, path+".securityContext", podSecurityContextFields)
+	}
+
+	if account, ok := fields["serviceAccountName"]; ok {
+		if account.Kind != yaml.ScalarNode {
+			v.addError(RuleType, account, path+".serviceAccountName must be string")
+		} else if len(account.Value) > maxSubdomainLength {
+			v.addError(RuleNameLength, account, fmt.Sprintf("%s exceeds %d characters", path+".serviceAccountName", maxSubdomainLength))
+		} else if !isDNSSubdomain(account.Value) {
+			v.addError(RuleNameFormat, account, path+".serviceAccountName has invalid format '"+account.Value+"'")
+		}
+	}
+	if account, ok := fields["serviceAccount"]; ok && v.Strict {
+		v.addWarning(RuleDeprecatedField, account, path+".serviceAccount is deprecated, use serviceAccountName instead")
+	}
+
+	if class, ok := fields["priorityClassName"]; ok && class.Kind != yaml.ScalarNode {
+		v.addError(RuleType, class, path+".priorityClassName must be string")
+	}
+	if priority, ok := fields["priority"]; ok {
+		if _, err := v.parseInt(priority); err != nil {
+			v.addError(RuleType, priority, path+".priority must be int")
+		} else if _, hasClass := fields["priorityClassName"]; !hasClass && v.Strict {
+			v.addWarning(RulePriorityClass, priority, path+".priority is set without priorityClassName")
+		}
+	}
+
+	if secrets, ok := fields["imagePullSecrets"]; ok {
+		v.validateImagePullSecrets(secrets, path+".imagePullSecrets")
+	}
+
+	if selector, ok := fields["nodeSelector"]; ok {
+		v.validateNodeSelector(selector, path+".nodeSelector")
+	}
+
+	if tolerations, ok := fields["tolerations"]; ok {
+		v.validateTolerations(tolerations, path+".tolerations")
+	}
+
+	dnsConfig, hasDNSConfig := fields["dnsConfig"]
+	if hasDNSConfig {
+		v.validateDNSConfig(dnsConfig, path+".dnsConfig")
+	}
+	if policy, ok := fields["dnsPolicy"]; ok {
+		if policy.Kind != yaml.ScalarNode {
+			v.addError(RuleType, policy, path+".dnsPolicy must be string")
+		} else if !validDNSPolicies[policy.Value] {
+			v.addError(RuleUnsupportedValue, policy, path+".dnsPolicy has unsupported value '"+policy.Value+"'")
+		} else if policy.Value == "None" && !hasDNSConfig {
+			v.addError(RuleRequired, nil, path+".dnsConfig is required when dnsPolicy is None")
+		}
+	}
+
+	if affinity, ok := fields["affinity"]; ok {
+		v.validateAffinity(affinity, path+".affinity")
+	}
+
+	if aliases, ok := fields["hostAliases"]; ok {
+		v.validateHostAliases(aliases, path+".hostAliases")
+	}
+
+	scope := &podScope{
+		path:           path,
+		containerNames: make(map[string]bool),
+		volumes:        make(map[string]bool),
+		hostPorts:      make(map[string]bool),
+	}
+	for _, key := range []string{"hostNetwork", "hostPID", "hostIPC"} {
+		if value, ok := fields[key]; ok {
+			enabled, _ := v.validateBool(value, path+"."+key)
+			if key == "hostNetwork" {
+				scope.hostNetwork = enabled
+			}
+		}
+	}
+	if volumes, ok := fields["volumes"]; ok {
+		v.validateVolumes(volumes, scope)
+	}
+
+	if containers, ok := fields["containers"]; !ok {
+		v.addError(RuleRequired, nil, path+".containers is required")
+	} else if containers.Kind == yaml.SequenceNode && len(containers.Content) == 0 {
+		v.addError(RuleNotEmpty, containers, path+".containers must not be empty")
+	} else {
+		v.validateContainers(containers, path+".containers", false, scope)
+		if scope.hostNetwork && containers.Kind == yaml.SequenceNode {
+			v.validateHostNetworkPorts(containers)
+		}
+	}
+
+	if initContainers, ok := fields["initContainers"]; ok {
+		v.validateContainers(initContainers, path+".initContainers", true, scope)
+	}
+}
+
+// validateHostNetworkPorts checks that no two containers declare the same
+// containerPort and protocol. With hostNetwork every containerPort is bound
+// on the node, so only one of them could listen on it. Ports that are not
+// valid have been reported already and are skipped.
+func (v *podValidator) validateHostNetworkPorts(containers *yaml.Node) {
+	owners := make(map[string]int)
+	for i, container := range containers.Content {
+		if container.Kind != yaml.MappingNode {
+			continue
+		}
+		ports, ok := v.parseMapping(container)["ports"]
+		if !ok || ports.Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, port := range ports.Content {
+			if port.Kind != yaml.MappingNode {
+				continue
+			}
+			fields := v.parseMapping(port)
+			containerPort, ok := fields["containerPort"]
+			if !ok {
+				continue
+			}
+			number, err := v.parseInt(containerPort)
+			if err != nil || number < minPort || number > maxPort {
+				continue
+			}
+			protocol := "TCP"
+			if proto, ok := fields["protocol"]; ok {
+				protocol = proto.Value
+			}
+			key := fmt.Sprintf("%d/%s", number, protocol)
+			if owner, ok := owners[key]; !ok {
+				owners[key] = i
+			} else if owner != i {
+				v.addError(RuleHostPortUnique, containerPort, fmt.Sprintf("containerPort %d conflicts across containers under hostNetwork", number))
+			}
+		}
+	}
+}
+
+func (v *podValidator) validateImagePullSecrets(node *yaml.Node, field string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, field+" must be a sequence")
+		return
+	}
+
+	for i, item := range node.Content {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, path+" must be a mapping")
+			continue
+		}
+
+		v.checkUnknownFields(item, path, localObjectReferenceFields)
+		if name, ok := v.parseMapping(item)["name"]; !ok {
+			v.addError(RuleRequired, nil, path+".name is required")
+		} else if name.Kind != yaml.ScalarNode || name.Value == "" {
+			v.addError(RuleType, name, path+".name must be string")
+		}
+	}
+}
+
+// validateNodeSelector checks that node maps label keys to string values.
+// Unquoted numbers and booleans are rejected, as the API server would.
+func (v *podValidator) validateNodeSelector(node *yaml.Node, field string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, field+" must be a mapping")
+		return
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode {
+			v.addError(RuleType, key, field+" key must be string")
+		}
+		if value.Kind != yaml.ScalarNode || value.Tag != "!!str" {
+			v.addError(RuleType, value, field+" value must be string")
+		}
+	}
+}
+
+func (v *podValidator) validateTolerations(node *yaml.Node, field string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, field+" must be a sequence")
+		return
+	}
+
+	for i, item := range node.Content {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, path+" must be a mapping")
+			continue
+		}
+
+		v.checkUnknownFields(item, path, tolerationFields)
+		fields := v.parseMapping(item)
+
+		for _, key := range []string{"key", "value"} {
+			if value, ok := fields[key]; ok && value.Kind != yaml.ScalarNode {
+				v.addError(RuleType, value, path+"."+key+" must be string")
+			}
+		}
+
+		if operator, ok := fields["operator"]; ok {
+			if operator.Kind != yaml.ScalarNode {
+				v.addError(RuleType, operator, path+".operator must be string")
+			} else if !validOperators[operator.Value] {
+				v.addError(RuleUnsupportedValue, operator, path+".operator has unsupported value '"+operator.Value+"'")
+			} else if value, ok := fields["value"]; ok && operator.Value == "Exists" && value.Value != "" {
+				v.addError(RuleTolerationValue, value, path+".value must be empty when operator is Exists")
+			}
+		}
+
+		if effect, ok := fields["effect"]; ok {
+			if effect.Kind != yaml.ScalarNode {
+				v.addError(RuleType, effect, path+".effect must be string")
+			} else if !validEffects[effect.Value] {
+				v.addError(RuleUnsupportedValue, effect, path+".effect has unsupported value '"+effect.Value+"'")
+			}
+		}
+
+		if seconds, ok := fields["tolerationSeconds"]; ok {
+			if _, err := v.parseInt(seconds); err != nil {
+				v.addError(RuleType, seconds, path+".tolerationSeconds must be int")
+			}
+		}
+	}
+}
+
+// validateAffinity checks the shape of the scheduling constraints in
+// affinity. Of the terms, only the label selectors of pod affinity terms
+// are validated.
+func (v *podValidator) validateAffinity(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, affinityFields)
+	fields := v.parseMapping(node)
+
+	if nodeAffinity, ok := fields["nodeAffinity"]; ok {
+		v.validateNodeAffinity(nodeAffinity, path+".nodeAffinity")
+	}
+	for _, key := range []string{"podAffinity", "podAntiAffinity"} {
+		affinity, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if affinity.Kind != yaml.MappingNode {
+			v.addError(RuleType, affinity, path+"."+key+" must be a mapping")
+			continue
+		}
+		v.checkUnknownFields(affinity, path+"."+key, podAffinityFields)
+		terms := v.parseMapping(affinity)
+		for _, field := range []string{"requiredDuringSchedulingIgnoredDuringExecution", "preferredDuringSchedulingIgnoredDuringExecution"} {
+			term, ok := terms[field]
+			if !ok {
+				continue
+			}
+			if term.Kind != yaml.SequenceNode {
+				v.addError(RuleType, term, path+"."+key+"."+field+" must be a sequence")
+				continue
+			}
+			for i, item := range term.Content {
+				v.validatePodAffinityTermSelector(item, fmt.Sprintf("%s.%s.%s[%d]", path, key, field, i))
+			}
+		}
+	}
+}
+
+// validatePodAffinityTermSelector validates the label selector of a pod
+// affinity term, which weighted terms wrap in podAffinityTerm.
+func (v *podValidator) validatePodAffinityTermSelector(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	fields := v.parseMapping(node)
+	if term, ok := fields["podAffinityTerm"]; ok && term.Kind == yaml.MappingNode {
+		path += ".podAffinityTerm"
+		fields = v.parseMapping(term)
+	}
+	if selector, ok := fields["labelSelector"]; ok {
+		v.validateLabelSelector(selector, path+".labelSelector")
+	}
+}
+
+func (v *podValidator) validateNodeAffinity(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, nodeAffinityFields)
+	fields := v.parseMapping(node)
+
+	if required, ok := fields["requiredDuringSchedulingIgnoredDuringExecution"]; ok {
+		requiredPath := path + ".requiredDuringSchedulingIgnoredDuringExecution"
+		if required.Kind != yaml.MappingNode {
+			v.addError(RuleType, required, requiredPath+" must be a mapping")
+		} else {
+			v.checkUnknownFields(required, requiredPath, nodeSelectorFields)
+			if terms, ok := v.parseMapping(required)["nodeSelectorTerms"]; !ok {
+				v.addError(RuleRequired, nil, requiredPath+".nodeSelectorTerms is required")
+			} else if terms.Kind != yaml.SequenceNode {
+				v.addError(RuleType, terms, requiredPath+".nodeSelectorTerms must be a sequence")
+			} else {
+				for i, term := range terms.Content {
+					if term.Kind != yaml.MappingNode {
+						v.addError(RuleType, term, fmt.Sprintf("%s.nodeSelectorTerms[%d] must be a mapping", requiredPath, i))
+					}
+				}
+			}
+		}
+	}
+
+	if preferred, ok := fields["preferredDuringSchedulingIgnoredDuringExecution"]; ok && preferred.Kind != yaml.SequenceNode {
+		v.addError(RuleType, preferred, path+".preferredDuringSchedulingIgnoredDuringExecution must be a sequence")
+	}
+}
+
+func (v *podValidator) validateDNSConfig(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, dnsConfigFields)
+	fields := v.parseMapping(node)
+
+	for _, key := range []string{"nameservers", "searches"} {
+		if list, ok := fields[key]; ok {
+			v.validateStringList(list, path+"."+key)
+		}
+	}
+
+	options, ok := fields["options"]
+	if !ok {
+		return
+	}
+	if options.Kind != yaml.SequenceNode {
+		v.addError(RuleType, options, path+".options must be a sequence")
+		return
+	}
+	for i, option := range options.Content {
+		optionPath := fmt.Sprintf("%s.options[%d]", path, i)
+		if option.Kind != yaml.MappingNode {
+			v.addError(RuleType, option, optionPath+" must be a mapping")
+			continue
+		}
+		v.checkUnknownFields(option, optionPath, dnsConfigOptionFields)
+		optionFields := v.parseMapping(option)
+		if name, ok := optionFields["name"]; !ok {
+			v.addError(RuleRequired, nil, optionPath+".name is required")
+		} else if name.Kind != yaml.ScalarNode || name.Value == "" {
+			v.addError(RuleType, name, optionPath+".name must be string")
+		}
+		if value, ok := optionFields["value"]; ok && value.Kind != yaml.ScalarNode {
+			v.addError(RuleType, value, optionPath+".value must be string")
+		}
+	}
+}
+
+func (v *podValidator) validateHostAliases(node *yaml.Node, field string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, field+" must be a sequence")
+		return
+	}
+
+	for i, item := range node.Content {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, path+" must be a mapping")
+			continue
+		}
+
+		v.checkUnknownFields(item, path, hostAliasFields)
+		fields := v.parseMapping(item)
+
+		if ip, ok := fields["ip"]; !ok {
+			v.addError(RuleRequired, nil, path+".ip is required")
+		} else if ip.Kind != yaml.ScalarNode {
+			v.addError(RuleType, ip, path+".ip must be string")
+		} else if net.ParseIP(ip.Value) == nil {
+			v.addError(RuleIPAddress, ip, path+".ip is not a valid IP address '"+ip.Value+"'")
+		}
+
+		if hostnames, ok := fields["hostnames"]; ok {
+			v.validateStringList(hostnames, path+".hostnames")
+			for j, hostname := range hostnames.Content {
+				if hostname.Kind == yaml.ScalarNode && hostname.Value == "" {
+					v.addError(RuleNotEmpty, hostname, fmt.Sprintf("%s.hostnames[%d] must not be empty", path, j))
+				}
+			}
+		}
+	}
+}
+
+// validateContainers validates the sequence of containers at field. Errors
+// name a single container by its index, such as spec.containers[1], and init
+// is set for spec.initContainers.
+func (v *podValidator) validateContainers(node *yaml.Node, field string, init bool, scope *podScope) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, field+" must be a sequence")
+		return
+	}
+
+	for i, container := range node.Content {
+		prefix := fmt.Sprintf("%s[%d]", field, i)
+		if container.Kind != yaml.MappingNode {
+			v.addError(RuleType, container, prefix+" must be a mapping")
+			continue
+		}
+		v.containers++
+		v.validateContainer(container, prefix, init, scope)
+	}
+}
+
+// podScope carries what the containers of a single pod need to know about
+// the pod and about each other.
+type podScope struct {
+	path           string
+	containerNames map[string]bool
+	volumes        map[string]bool
+	hostPorts      map[string]bool
+	hostNetwork    bool
+}
+
+// validateVolumes checks spec.volumes and records the declared volume names
+// in scope so that volume mounts can be checked against them.
+func (v *podValidator) validateVolumes(node *yaml.Node, scope *podScope) {
+	path := scope.path + ".volumes"
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, path+" must be a sequence")
+		return
+	}
+
+	for i, item := range node.Content {
+		prefix := fmt.Sprintf("%s[%d]", path, i)
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, prefix+" must be a mapping")
+			continue
+		}
+
+		v.checkUnknownFields(item, prefix, volumeFields)
+		fields := v.parseMapping(item)
+		name, ok := fields["name"]
+		if !ok {
+			v.addError(RuleRequired, nil, prefix+".name is required")
+		} else if name.Kind != yaml.ScalarNode || name.Value == "" {
+			v.addError(RuleType, name, prefix+".name must be string")
+		} else if scope.volumes[name.Value] {
+			v.addError(RuleVolumeNameUnique, name, prefix+".name must be unique within pod")
+		} else {
+			scope.volumes[name.Value] = true
+		}
+
+		v.validateVolumeSource(item, fields, prefix)
+	}
+}
+
+// validateVolumeSource checks that the fields of volume name exactly one
+// source and does light checks on the common ones.
+func (v *podValidator) validateVolumeSource(volume *yaml.Node, fields map[string]*yaml.Node, path string) {
+	var sources []string
+	for _, source := range volumeSources {
+		if _, ok := fields[source]; ok {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) != 1 {
+		// Point at the second source if there are several, or at the
+		// volume itself if there is none.
+		at := volume
+		if len(sources) > 1 {
+			at = fields[sources[1]]
+		}
+		v.addError(RuleVolumeSource, at, path+" must specify exactly one volume source")
+		return
+	}
+
+	source := sources[0]
+	node := fields[source]
+	path += "." + source
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	switch source {
+	case "hostPath":
+		v.checkUnknownFields(node, path, hostPathVolumeFields)
+		if p, ok := v.parseMapping(node)["path"]; !ok {
+			v.addError(RuleRequired, nil, path+".path is required")
+		} else if p.Kind != yaml.ScalarNode || !strings.HasPrefix(p.Value, "/") {
+			v.addError(RuleAbsolutePath, p, path+".path must be absolute path")
+		}
+	case "emptyDir":
+		v.checkUnknownFields(node, path, emptyDirVolumeFields)
+		v.validateEmptyDir(node, path)
+	case "configMap":
+		v.checkUnknownFields(node, path, configMapVolumeFields)
+		v.validateVolumeSourceName(node, path, "name", false)
+	case "secret":
+		v.checkUnknownFields(node, path, secretVolumeFields)
+		v.validateVolumeSourceName(node, path, "secretName", false)
+	case "persistentVolumeClaim":
+		v.checkUnknownFields(node, path, pvcVolumeFields)
+		v.validateVolumeSourceName(node, path, "claimName", true)
+		if readOnly, ok := v.parseMapping(node)["readOnly"]; ok {
+			v.validateBool(readOnly, path+".readOnly")
+		}
+	}
+}
+
+// validateEmptyDir checks the storage medium and size limit of an emptyDir
+// volume.
+func (v *podValidator) validateEmptyDir(node *yaml.Node, path string) {
+	fields := v.parseMapping(node)
+
+	if medium, ok := fields["medium"]; ok {
+		if medium.Kind != yaml.ScalarNode {
+			v.addError(RuleType, medium, path+".medium must be string")
+		} else if !validMedia[medium.Value] {
+			v.addError(RuleUnsupportedValue, medium, path+".medium has unsupported value '"+medium.Value+"'")
+		}
+	}
+
+	if limit, ok := fields["sizeLimit"]; ok {
+		if limit.Kind != yaml.ScalarNode {
+			v.addError(RuleType, limit, path+".sizeLimit must be string")
+		} else if _, err := ParseMemory(limit.Value); errors.Is(err, errMemoryOverflow) {
+			v.addError(RuleMemoryFormat, limit, path+".sizeLimit value out of range")
+		} else if err != nil {
+			v.addError(RuleMemoryFormat, limit, path+".sizeLimit has invalid format '"+limit.Value+"' (expected an integer with one of the suffixes "+memoryUnitList+")")
+		}
+	}
+}
+
+// validateVolumeSourceName checks the field of a volume source that names
+// the object it refers to.
+func (v *podValidator) validateVolumeSourceName(node *yaml.Node, path, key string, required bool) {
+	name, ok := v.parseMapping(node)[key]
+	if !ok {
+		if required {
+			v.addError(RuleRequired, nil, path+"."+key+" is required")
+		}
+		return
+	}
+	if name.Kind != yaml.ScalarNode || name.Value == "" {
+		v.addError(RuleType, name, path+"."+key+" must be string")
+	}
+}
+
+func (v *podValidator) validatePodOS(node *yaml.Node, path string) {
+	v.checkUnknownFields(node, path, podOSFields)
+	fields := v.parseMapping(node)
+
+	name, ok := fields["name"]
+	if !ok {
+		v.addError(RuleRequired, nil, path+".name is required")
+		return
+	}
+	if !validOSNames[name.Value] {
+		v.addError(RuleUnsupportedValue, name, path+" has unsupported value '"+name.Value+"'")
+	}
+}
+
+func (v *podValidator) validateContainer(node *yaml.Node, prefix string, init bool, scope *podScope) {
+	v.checkUnknownFields(node, prefix, containerFields)
+	fields := v.parseMapping(node)
+
+	if nameNode, ok := fields["name"]; !ok {
+		v.addError(RuleRequired, nil, prefix+".name is required")
+	} else if nameNode.Kind != yaml.ScalarNode {
+		v.addError(RuleType, nameNode, prefix+".name must be string")
+	} else if len(nameNode.Value) > maxLabelLength {
+		v.addError(RuleNameLength, nameNode, fmt.Sprintf("%s exceeds %d characters", prefix+".name", maxLabelLength))
+	} else if pattern := v.namePattern(); !pattern.MatchString(nameNode.Value) {
+		v.addError(RuleContainerName, nameNode, prefix+".name has invalid format '"+nameNode.Value+"' (must match '"+pattern.String()+"')")
+	} else if scope.containerNames[nameNode.Value] {
+		v.addError(RuleContainerNameUnique, nameNode, prefix+".name must be unique within pod")
+	} else {
+		scope.containerNames[nameNode.Value] = true
+	}
+
+	if imageNode, ok := fields["image"]; !ok {
+		v.addError(RuleRequired, nil, prefix+".image is required")
+	} else if imageNode.Kind != yaml.ScalarNode || imageNode.Value == "" {
+		v.addError(RuleType, imageNode, prefix+".image must be string")
+	} else {
+		v.validateImage(imageNode, prefix)
+	}
+
+	// Since Kubernetes 1.28 an init container with restartPolicy Always is
+	// a sidecar that keeps running next to the containers. No other value
+	// is accepted, and regular containers take no restartPolicy of their own.
+	if policy, ok := fields["restartPolicy"]; ok {
+		if !init {
+			v.addError(RuleContainerRestartPolicy, policy, prefix+".restartPolicy is only supported on initContainers")
+		} else if policy.Kind != yaml.ScalarNode {
+			v.addError(RuleType, policy, prefix+".restartPolicy must be string")
+		} else if policy.Value != "Always" {
+			v.addError(RuleContainerRestartPolicy, policy, prefix+".restartPolicy must be Always for a sidecar")
+		}
+	}
+
+	if policy, ok := fields["imagePullPolicy"]; ok {
+		if policy.Kind != yaml.ScalarNode {
+			v.addError(RuleType, policy, prefix+".imagePullPolicy must be string")
+		} else if !validPullPolicies[policy.Value] {
+			v.addError(RuleUnsupportedValue, policy, prefix+".imagePullPolicy has unsupported value '"+policy.Value+"'")
+		}
+	}
+
+	for _, key := range []string{"command", "args"} {
+		if list, ok := fields[key]; ok {
+			v.validateStringList(list, prefix+"."+key)
+		}
+	}
+
+	stdin, stdinOK := false, true
+	if node, ok := fields["stdin"]; ok {
+		stdin, stdinOK = v.validateBool(node, prefix+".stdin")
+	}
+	if tty, ok := fields["tty"]; ok {
+		v.validateBool(tty, prefix+".tty")
+	}
+	if node, ok := fields["stdinOnce"]; ok {
+		if once, ok := v.validateBool(node, prefix+".stdinOnce"); ok && once && stdinOK && !stdin {
+			v.addError(RuleStdinOnce, node, prefix+".stdinOnce requires stdin to be true")
+		}
+	}
+
+	portNames := make(map[string]bool)
+	if portsNode, ok := fields["ports"]; ok {
+		if portsNode.Kind != yaml.SequenceNode {
+			v.addError(RuleType, portsNode, prefix+".ports must be a sequence")
+		} else {
+			for i, port := range portsNode.Content {
+				v.validateContainerPort(port, fmt.Sprintf(prefix+".ports[%d]", i), scope, portNames)
+			}
+		}
+	}
+
+	privileged := false
+	if sc, ok := fields["securityContext"]; ok {
+		privileged = v.validateSecurityContext(sc, prefix+".securityContext", securityContextFields)
+	}
+
+	if env, ok := fields["env"]; ok {
+		v.validateEnv(env, prefix)
+	}
+
+	if envFrom, ok := fields["envFrom"]; ok {
+		v.validateEnvFrom(envFrom, prefix)
+	}
+
+	if mounts, ok := fields["volumeMounts"]; ok {
+		v.validateVolumeMounts(mounts, prefix, scope, privileged)
+	}
+
+	for _, key := range []string{"readinessProbe", "livenessProbe", "startupProbe"} {
+		if probe, ok := fields[key]; ok {
+			if probe.Kind != yaml.MappingNode {
+				v.addError(RuleType, probe, prefix+"."+key+" must be a mapping")
+			} else {
+				v.validateProbe(probe, prefix+"."+key, portNames)
+			}
+		}
+	}
+
+	if lifecycle, ok := fields["lifecycle"]; ok {
+		v.validateLifecycle(lifecycle, prefix+".lifecycle", portNames)
+	}
+
+	if resources, ok := fields["resources"]; !ok {
+		v.addError(RuleRequired, nil, prefix+".resources is required")
+	} else if resources.Kind != yaml.MappingNode {
+		v.addError(RuleType, resources, prefix+".resources must be a mapping")
+	} else {
+		v.validateResourceRequirements(resources, prefix)
+	}
+}
+
+func (v *podValidator) validateImage(node *yaml.Node, prefix string) {
+	image := node.Value
+	parts := strings.Split(image, "/")
+	if len(parts) < 2 {
+		v.addError(RuleImageFormat, node, prefix+".image has invalid format '"+image+"'")
+		return
+	}
+	if !v.allowedRegistry(image) {
+		v.addError(RuleImageRegistry, node, prefix+".image must be in "+v.describeRegistries())
+		return
+	}
+
+	if repository := imageRepository(image); repository != strings.ToLower(repository) {
+		v.addError(RuleImageFormat, node, prefix+".image repository must be lowercase")
+	}
+
+	// A digest pins the image on its own; a tag next to it is ignored by
+	// the runtime and not checked.
+	if _, digest, found := strings.Cut(image, "@"); found {
+		if !imageDigestRegex.MatchString(digest) {
+			v.addError(RuleImageDigest, node, prefix+".image has invalid digest '"+digest+"'")
+		}
+		return
+	}
+	if v.RequireDigest {
+		v.addError(RuleImageDigest, node, prefix+".image must be pinned by digest")
+		return
+	}
+
+	lastPart := parts[len(parts)-1]
+	if !strings.Contains(lastPart, ":") {
+		v.addError(RuleImageTag, node, prefix+".image tag is required in '"+image+"'")
+		return
+	}
+	_, tag, _ := strings.Cut(lastPart, ":")
+	if tag == "" {
+		v.addError(RuleImageTag, node, prefix+".image tag is required in '"+image+"'")
+		return
+	}
+	if !imageTagRegex.MatchString(tag) {
+		v.addError(RuleImageTag, node, prefix+".image has invalid tag '"+tag+"'")
+		return
+	}
+	if v.forbiddenTag(tag) {
+		v.addError(RuleImageLatest, node, prefix+".image uses disallowed tag '"+tag+"'")
+	}
+}
+
+// imageRepository returns the repository of image, the path between the
+// registry host and the tag or digest.
+func imageRepository(image string) string {
+	name, _, _ := strings.Cut(image, "@")
+	_, repository, _ := strings.Cut(name, "/")
+	if i := strings.LastIndex(repository, ":"); i > strings.LastIndex(repository, "/") {
+		repository = repository[:i]
+	}
+	return repository
+}
+
+// forbiddenTag reports whether images must not use tag.
+func (v *podValidator) forbiddenTag(tag string) bool {
+	if v.NoLatest && tag == "latest" {
+		return true
+	}
+	for _, forbidden := range v.ForbiddenTags {
+		if tag == forbidden {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *podValidator) registries() []string {
+	if len(v.Registries) == 0 {
+		return []string{domainRequired}
+	}
+	return v.Registries
+}
+
+func (v *podValidator) allowedRegistry(image string) bool {
+	for _, registry := range v.registries() {
+		if strings.HasPrefix(image, strings.TrimSuffix(registry, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *podValidator) describeRegistries() string {
+	registries := v.registries()
+	if len(registries) == 1 {
+		return "domain '" + registries[0] + "'"
+	}
+	return "one of domains '" + strings.Join(registries, "', '") + "'"
+}
+
+// validateSecurityContext checks the types of the security settings shared
+// by pod and container security contexts. known differs between the two and
+// is only used in strict mode. It reports whether privileged is true.
+func (v *podValidator) validateSecurityContext(node *yaml.Node, path string, known map[string]bool) (privileged bool) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return false
+	}
+
+	v.checkUnknownFields(node, path, known)
+	fields := v.parseMapping(node)
+
+	for _, key := range []string{"runAsUser", "runAsGroup"} {
+		if value, ok := fields[key]; ok {
+			if _, err := v.parseInt(value); err != nil {
+				v.addError(RuleType, value, path+"."+key+" must be int")
+			}
+		}
+	}
+
+	for _, key := range []string{"runAsNonRoot", "readOnlyRootFilesystem", "privileged", "allowPrivilegeEscalation"} {
+		if value, ok := fields[key]; ok {
+			enabled, _ := v.validateBool(value, path+"."+key)
+			if key == "privileged" {
+				privileged = enabled
+			}
+		}
+	}
+	return privileged
+}
+
+func (v *podValidator) validateEnv(node *yaml.Node, prefix string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, prefix+".env must be a sequence")
+		return
+	}
+
+	for i, item := range node.Content {
+		path := fmt.Sprintf("%s.env[%d]", prefix, i)
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, path+" must be a mapping")
+			continue
+		}
+
+		v.checkUnknownFields(item, path, envVarFields)
+		fields := v.parseMapping(item)
+
+		if name, ok := fields["name"]; !ok {
+			v.addError(RuleRequired, nil, path+".name is required")
+		} else if name.Kind != yaml.ScalarNode {
+			v.addError(RuleType, name, path+".name must be string")
+		} else if !envVarNameRegex.MatchString(name.Value) {
+			v.addError(RuleEnvName, name, path+".name has invalid format '"+name.Value+"'")
+		}
+
+		value, hasValue := fields["value"]
+		if hasValue && (value.Kind != yaml.ScalarNode || value.Tag != "!!str") {
+			v.addError(RuleType, value, path+".value must be string")
+		}
+		valueFrom, hasValueFrom := fields["valueFrom"]
+		if hasValueFrom {
+			v.validateEnvValueFrom(valueFrom, path+".valueFrom")
+		}
+		if hasValue && hasValueFrom {
+			v.addError(RuleEnvSource, valueFrom, path+" must not set both value and valueFrom")
+		}
+	}
+}
+
+// envVarSources lists the sources of an environment variable value with the
+// fields each of them requires.
+var envVarSources = []struct {
+	key      string
+	known    map[string]bool
+	required []string
+}{
+	{"fieldRef", objectFieldSelectorFields, []string{"fieldPath"}},
+	{"resourceFieldRef", resourceFieldSelectorFields, []string{"resource"}},
+	{"configMapKeyRef", keySelectorFields, []string{"name", "key"}},
+	{"secretKeyRef", keySelectorFields, []string{"name", "key"}},
+}
+
+// validateEnvValueFrom checks that valueFrom sets exactly one source and
+// that the source has the fields it requires.
+func (v *podValidator) validateEnvValueFrom(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, envVarSourceFields)
+	fields := v.parseMapping(node)
+
+	var found []string
+	for _, source := range envVarSources {
+		sourceNode, ok := fields[source.key]
+		if !ok {
+			continue
+		}
+		found = append(found, source.key)
+
+		sourcePath := path + "." + source.key
+		if sourceNode.Kind != yaml.MappingNode {
+			v.addError(RuleType, sourceNode, sourcePath+" must be a mapping")
+			continue
+		}
+		v.checkUnknownFields(sourceNode, sourcePath, source.known)
+		sourceFields := v.parseMapping(sourceNode)
+		for _, key := range source.required {
+			if value, ok := sourceFields[key]; !ok {
+				v.addError(RuleRequired, nil, sourcePath+"."+key+" is required")
+			} else if value.Kind != yaml.ScalarNode || value.Value == "" {
+				v.addError(RuleType, value, sourcePath+"."+key+" must be string")
+			}
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		v.addError(RuleEnvSource, node, path+" must set one of fieldRef, resourceFieldRef, configMapKeyRef, secretKeyRef")
+	case 1:
+	default:
+		v.addError(RuleEnvSource, fields[found[1]], path+" must set only one of fieldRef, resourceFieldRef, configMapKeyRef, secretKeyRef")
+	}
+}
+
+// validateEnvFrom checks that each envFrom item imports exactly one config
+// map or secret.
+func (v *podValidator) validateEnvFrom(node *yaml.Node, prefix string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, prefix+".envFrom must be a sequence")
+		return
+	}
+
+	for i, item := range node.Content {
+		path := fmt.Sprintf("%s.envFrom[%d]", prefix, i)
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, path+" must be a mapping")
+			continue
+		}
+
+		v.checkUnknownFields(item, path, envFromSourceFields)
+		fields := v.parseMapping(item)
+
+		if p, ok := fields["prefix"]; ok && p.Kind != yaml.ScalarNode {
+			v.addError(RuleType, p, path+".prefix must be string")
+		}
+
+		configMapRef, hasConfigMap := fields["configMapRef"]
+		secretRef, hasSecret := fields["secretRef"]
+		switch {
+		case hasConfigMap && hasSecret:
+			v.addError(RuleEnvSource, secretRef, path+" must not set both configMapRef and secretRef")
+		case !hasConfigMap && !hasSecret:
+			v.addError(RuleEnvSource, item, path+" must set one of configMapRef, secretRef")
+		}
+		if hasConfigMap {
+			v.validateEnvSourceRef(configMapRef, path+".configMapRef")
+		}
+		if hasSecret {
+			v.validateEnvSourceRef(secretRef, path+".secretRef")
+		}
+	}
+}
+
+// validateEnvSourceRef checks a reference to a config map or secret.
+func (v *podValidator) validateEnvSourceRef(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, envSourceRefFields)
+	fields := v.parseMapping(node)
+
+	if name, ok := fields["name"]; !ok {
+		v.addError(RuleRequired, nil, path+".name is required")
+	} else if name.Kind != yaml.ScalarNode || name.Value == "" {
+		v.addError(RuleType, name, path+".name must be string")
+	}
+	if optional, ok := fields["optional"]; ok {
+		v.validateBool(optional, path+".optional")
+	}
+}
+
+func (v *podValidator) validateVolumeMounts(node *yaml.Node, prefix string, scope *podScope, privileged bool) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, prefix+".volumeMounts must be a sequence")
+		return
+	}
+
+	for i, item := range node.Content {
+		path := fmt.Sprintf("%s.volumeMounts[%d]", prefix, i)
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, path+" must be a mapping")
+			continue
+		}
+
+		v.checkUnknownFields(item, path, volumeMountFields)
+		fields := v.parseMapping(item)
+
+		if name, ok := fields["name"]; !ok {
+			v.addError(RuleRequired, nil, path+".name is required")
+		} else if name.Kind != yaml.ScalarNode || name.Value == "" {
+			v.addError(RuleType, name, path+".name must be string")
+		} else if !scope.volumes[name.Value] {
+			v.addError(RuleVolumeNotFound, name, path+".name '"+name.Value+"' not found in "+scope.path+".volumes")
+		}
+
+		if mountPath, ok := fields["mountPath"]; !ok {
+			v.addError(RuleRequired, nil, path+".mountPath is required")
+		} else if mountPath.Kind != yaml.ScalarNode || !strings.HasPrefix(mountPath.Value, "/") {
+			v.addError(RuleAbsolutePath, mountPath, path+".mountPath must be absolute path")
+		}
+
+		for _, key := range []string{"subPath", "subPathExpr"} {
+			if value, ok := fields[key]; ok && value.Kind != yaml.ScalarNode {
+				v.addError(RuleType, value, path+"."+key+" must be string")
+			}
+		}
+		subPath, hasSubPath := fields["subPath"]
+		if expr, ok := fields["subPathExpr"]; ok && hasSubPath {
+			v.addError(RuleSubPath, expr, path+" cannot set both subPath and subPathExpr")
+		}
+		if hasSubPath && subPath.Kind == yaml.ScalarNode && hasParentReference(subPath.Value) {
+			v.addError(RuleSubPath, subPath, path+".subPath must not contain '..'")
+		}
+
+		if readOnly, ok := fields["readOnly"]; ok {
+			v.validateBool(readOnly, path+".readOnly")
+		}
+
+		if propagation, ok := fields["mountPropagation"]; ok {
+			if propagation.Kind != yaml.ScalarNode {
+				v.addError(RuleType, propagation, path+".mountPropagation must be string")
+			} else if !validPropagations[propagation.Value] {
+				v.addError(RuleUnsupportedValue, propagation, path+".mountPropagation has unsupported value '"+propagation.Value+"'")
+			} else if propagation.Value == "Bidirectional" && !privileged {
+				v.addError(RuleMountPropagation, propagation, path+".mountPropagation Bidirectional requires "+prefix+".securityContext.privileged")
+			}
+		}
+	}
+}
+
+// hasParentReference reports whether path has a ".." element, which could
+// reach outside the volume it is resolved in.
+func hasParentReference(path string) bool {
+	for _, element := range strings.Split(path, "/") {
+		if element == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// validateContainerPort checks a single entry of a container's ports.
+// portNames collects the port names seen so far in the container.
+func (v *podValidator) validateContainerPort(node *yaml.Node, path string, scope *podScope, portNames map[string]bool) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, containerPortFields)
+	fields := v.parseMapping(node)
+
+	containerPort, ok := fields["containerPort"]
+	if !ok {
+		v.addError(RuleRequired, nil, path+".containerPort is required")
+	} else {
+		v.validatePort(containerPort, path+".containerPort")
+	}
+
+	// With hostNetwork the container listens on the node directly, so a
+	// hostPort that differs from containerPort cannot take effect.
+	if hostPort, ok := fields["hostPort"]; ok && containerPort != nil && v.Strict && scope.hostNetwork {
+		if hostPort.Value != containerPort.Value {
+			v.addWarning(RuleHostPort, hostPort, path+".hostPort must equal containerPort when "+scope.path+".hostNetwork is true")
+		}
+	}
+
+	if name, ok := fields["name"]; ok {
+		if name.Kind != yaml.ScalarNode {
+			v.addError(RuleType, name, path+".name must be string")
+		} else if len(name.Value) > maxPortNameLength {
+			v.addError(RuleNameLength, name, fmt.Sprintf("%s exceeds %d characters", path+".name", maxPortNameLength))
+		} else if !isPortName(name.Value) {
+			v.addError(RulePortName, name, path+".name has invalid format '"+name.Value+"'")
+		} else if portNames[name.Value] {
+			v.addError(RulePortNameUnique, name, path+".name '"+name.Value+"' is duplicated")
+		} else {
+			portNames[name.Value] = true
+		}
+	}
+
+	if proto, ok := fields["protocol"]; ok {
+		if proto.Kind != yaml.ScalarNode {
+			v.addError(RuleType, proto, path+".protocol must be string")
+		} else if !validProtocols[proto.Value] {
+			v.addError(RuleUnsupportedValue, proto, path+".protocol has unsupported value '"+proto.Value+"' (expected one of "+protocolList+")")
+		}
+	} else if v.Strict {
+		v.addWarning(RulePortProtocol, node, path+".protocol is not set and defaults to TCP")
+	}
+
+	// A port claims hostIP:hostPort/protocol on the node. Kubernetes
+	// defaults the protocol to TCP and the host IP to all addresses.
+	if hostPort, ok := fields["hostPort"]; ok {
+		if port, ok := v.validatePort(hostPort, path+".hostPort"); ok {
+			protocol := "TCP"
+			if proto, ok := fields["protocol"]; ok {
+				protocol = proto.Value
+			}
+			hostIP := ""
+			if ip, ok := fields["hostIP"]; ok {
+				hostIP = ip.Value
+			}
+			key := fmt.Sprintf("%s:%d/%s", hostIP, port, protocol)
+			if scope.hostPorts[key] {
+				v.addError(RuleHostPortUnique, hostPort, fmt.Sprintf("%s.hostPort %d is used more than once", path, port))
+			}
+			scope.hostPorts[key] = true
+		}
+	}
+}
+
+func (v *podValidator) validateProbe(node *yaml.Node, path string, portNames map[string]bool) {
+	v.checkUnknownFields(node, path, probeFields)
+	v.validateHandler(node, path, portNames)
+}
+
+// validateLifecycle checks the postStart and preStop hooks of a container.
+// Each hook is a handler like the one of a probe.
+func (v *podValidator) validateLifecycle(node *yaml.Node, path string, portNames map[string]bool) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, lifecycleFields)
+	fields := v.parseMapping(node)
+
+	for _, key := range []string{"postStart", "preStop"} {
+		hook, ok := fields[key]
+		if !ok {
+			continue
+		}
+		if hook.Kind != yaml.MappingNode {
+			v.addError(RuleType, hook, path+"."+key+" must be a mapping")
+			continue
+		}
+		v.checkUnknownFields(hook, path+"."+key, lifecycleHandlerFields)
+		v.validateHandler(hook, path+"."+key, portNames)
+	}
+}
+
+// validateHandler checks that node, a probe or lifecycle hook, specifies
+// exactly one handler and validates it. portNames holds the names of
+// the container's ports, which handlers may refer to instead of a number.
+func (v *podValidator) validateHandler(node *yaml.Node, field string, portNames map[string]bool) {
+	fields := v.parseMapping(node)
+	var handlers []string
+	for _, handler := range probeHandlers {
+		if _, ok := fields[handler]; ok {
+			handlers = append(handlers, handler)
+		}
+	}
+	switch len(handlers) {
+	case 0:
+		v.addError(RuleProbeHandler, node, field+" must specify one of "+strings.Join(probeHandlers, ", "))
+		return
+	case 1:
+	default:
+		v.addError(RuleProbeHandler, fields[handlers[1]], field+" must specify only one of "+strings.Join(probeHandlers, ", "))
+	}
+
+	for _, handler := range handlers {
+		path := field + "." + handler
+		switch handler {
+		case "httpGet":
+			v.validateHTTPGetAction(fields[handler], path, portNames)
+		case "tcpSocket":
+			v.validateTCPSocketAction(fields[handler], path, portNames)
+		case "exec":
+			v.validateExecAction(fields[handler], path)
+		}
+	}
+}
+
+func (v *podValidator) validateHTTPGetAction(node *yaml.Node, path string, portNames map[string]bool) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, httpGetFields)
+	fields := v.parseMapping(node)
+
+	if p, ok := fields["path"]; !ok {
+		v.addError(RuleRequired, nil, path+".path is required")
+	} else if p.Kind != yaml.ScalarNode || !strings.HasPrefix(p.Value, "/") {
+		v.addError(RuleProbePath, p, path+".path must be absolute path")
+	} else if !isURLPath(p.Value) {
+		v.addError(RuleProbePath, p, path+".path has invalid format '"+p.Value+"'")
+	}
+
+	if portNode, ok := fields["port"]; !ok {
+		v.addError(RuleRequired, nil, path+".port is required")
+	} else {
+		v.validateNamedPort(portNode, path+".port", portNames)
+	}
+
+	if scheme, ok := fields["scheme"]; ok {
+		if scheme.Kind != yaml.ScalarNode {
+			v.addError(RuleType, scheme, path+".scheme must be string")
+		} else if !validSchemes[scheme.Value] {
+			v.addError(RuleUnsupportedValue, scheme, path+".scheme has unsupported value '"+scheme.Value+"'")
+		}
+	}
+
+	if headers, ok := fields["httpHeaders"]; ok {
+		v.validateHTTPHeaders(headers, path+".httpHeaders")
+	}
+}
+
+func (v *podValidator) validateHTTPHeaders(node *yaml.Node, field string) {
+	if node.Kind != yaml.SequenceNode {
+		v.addError(RuleType, node, field+" must be a sequence")
+		return
+	}
+
+	for i, item := range node.Content {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		if item.Kind != yaml.MappingNode {
+			v.addError(RuleType, item, path+" must be a mapping")
+			continue
+		}
+
+		v.checkUnknownFields(item, path, httpHeaderFields)
+		fields := v.parseMapping(item)
+
+		for _, key := range []string{"name", "value"} {
+			if value, ok := fields[key]; !ok {
+				v.addError(RuleRequired, nil, path+"."+key+" is required")
+			} else if value.Kind != yaml.ScalarNode {
+				v.addError(RuleType, value, path+"."+key+" must be string")
+			}
+		}
+	}
+}
+
+// isPortName reports whether s is an IANA service name: at most 15 lowercase
+// letters, digits and hyphens with at least one letter, where hyphens are
+// neither leading, trailing nor adjacent.
+func isPortName(s string) bool {
+	return len(s) <= maxPortNameLength && portNameRegex.MatchString(s) && !strings.Contains(s, "--") && strings.ContainsAny(s, "abcdefghijklmnopqrstuvwxyz")
+}
+
+// isURLPath reports whether s is a plain URL path: no whitespace, query or
+// fragment, and no leading "//", which url.Parse would read as a host.
+func isURLPath(s string) bool {
+	if strings.ContainsAny(s, " \t\r\n?#") || strings.HasPrefix(s, "//") {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme == "" && u.Host == "" && u.EscapedPath() == s
+}
+
+func (v *podValidator) validateTCPSocketAction(node *yaml.Node, path string, portNames map[string]bool) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, tcpSocketFields)
+	fields := v.parseMapping(node)
+
+	if portNode, ok := fields["port"]; !ok {
+		v.addError(RuleRequired, nil, path+".port is required")
+	} else {
+		v.validateNamedPort(portNode, path+".port", portNames)
+	}
+
+	if host, ok := fields["host"]; ok && host.Kind != yaml.ScalarNode {
+		v.addError(RuleType, host, path+".host must be string")
+	}
+}
+
+func (v *podValidator) validateExecAction(node *yaml.Node, path string) {
+	if node.Kind != yaml.MappingNode {
+		v.addError(RuleType, node, path+" must be a mapping")
+		return
+	}
+
+	v.checkUnknownFields(node, path, execFields)
+	fields := v.parseMapping(node)
+
+	if command, ok := fields["command"]; !ok {
+		v.addError(RuleRequired, nil, path+".command is required")
+	} else if command.Kind == yaml.SequenceNode && len(command.Content) == 0 {
+		v.addError(RuleNotEmpty, command, path+".command must not be empty")
+	} else {
+		v.validateStringList(command, path+".command")
+	}
+}
+
+// validateNamedPort checks a port that is either a number or, as a string,
+// the name of one of the container's ports.
+func (v *podValidator) validateNamedPort(node *yaml.Node, field string, portNames map[string]bool) {
+	if node.Kind != yaml.ScalarNode || node.Tag != "!!str" {
+		v.validatePort(node, field)
+		return
+	}
+	if !portNames[node.Value] {
+		v.addError(RulePortNotFound, node, field+" '"+node.Value+"' does not match any declared containerPort name")
+	}
+}
+
+// validatePort checks that node is an int within the TCP/UDP port range
+// 1-65535 and returns it. ok is false if it is not.
+func (v *podValidator) validatePort(node *yaml.Node, field string) (port int, ok bool) {
+	port, err := v.parseInt(node)
+	if err != nil {
+		v.addError(RuleType, node, field+" must be int")
+		return 0, false
+	}
+	if port < minPort || port > maxPort {
+		v.addError(RulePortRange, node, fmt.Sprintf("%s must be between %d and %d", field, minPort, maxPort))
+		return 0, false
+	}
+	return port, true
+}
+
+func (v *podValidator) validateResourceRequirements(node *yaml.Node, prefix string) {
+	v.checkUnknownFields(node, prefix+".resources", resourceRequirementsFields)
+	fields := v.parseMapping(node)
+
+	req, hasRequests := fields["requests"]
+	if hasRequests {
+		if req.Kind != yaml.MappingNode {
+			v.addError(RuleType, req, prefix+".resources.requests must be a mapping")
+		} else {
+			v.validateResourceMap(req, prefix+".resources.requests")
+		}
+	}
+	if v.RequireRequests && (!hasRequests || req.Kind == yaml.MappingNode) {
+		requests := make(map[string]*yaml.Node)
+		if hasRequests {
+			requests = v.parseMapping(req)
+		}
+		for _, key := range []string{"cpu", "memory"} {
+			if _, ok := requests[key]; !ok {
+				v.addError(RuleRequired, nil, prefix+".resources.requests."+key+" is required")
+			}
+		}
+	}
+
+	lim, hasLimits := fields["limits"]
+	if hasLimits {
+		if lim.Kind != yaml.MappingNode {
+			v.addError(RuleType, lim, prefix+".resources.limits must be a mapping")
+		} else {
+			v.validateResourceMap(lim, prefix+".resources.limits")
+		}
+	}
+
+	if hasRequests && hasLimits {
+		v.validateRequestsWithinLimits(v.parseMapping(req), v.parseMapping(lim), prefix)
+	}
+}
+
+// validateRequestsWithinLimits checks that no resource requests more than its
+// limit. Resources missing from either side, or with values that do not
+// parse, are skipped; the latter are already reported by validateResourceMap.
+func (v *podValidator) validateRequestsWithinLimits(requests, limits map[string]*yaml.Node, prefix string) {
+	parsers := []struct {
+		key   string
+		parse func(string) (int64, error)
+	}{
+		{"cpu", parseCPU},
+		{"memory", ParseMemory},
+		{"ephemeral-storage", ParseMemory},
+	}
+	for _, p := range parsers {
+		reqNode, ok := requests[p.key]
+		if !ok {
+			continue
+		}
+		limNode, ok := limits[p.key]
+		if !ok {
+			continue
+		}
+		reqValue, err := p.parse(reqNode.Value)
+		if err != nil {
+			continue
+		}
+		limValue, err := p.parse(limNode.Value)
+		if err != nil {
+			continue
+		}
+		if reqValue > limValue {
+			v.addError(RuleRequestsExceedLimits, reqNode, prefix+".resources.requests."+p.key+" ("+reqNode.Value+") exceeds resources.limits."+p.key+" ("+limNode.Value+")")
+		}
+	}
+}
+
+func (v *podValidator) validateResourceMap(node *yaml.Node, path string) {
+	for i := 0; i < len(node.Content); i += 2 {
+		keyNode := node.Content[i]
+		valueNode := node.Content[i+1]
+
+		if keyNode.Kind != yaml.ScalarNode {
+			v.addError(RuleType, keyNode, path+" keys must be strings")
+			continue
+		}
+
+		key := keyNode.Value
+		if !validResourceKeys[key] && !v.extendedResource(key) {
+			v.addError(RuleResourceName, keyNode, path+" has unsupported resource '"+key+"'")
+			continue
+		}
+
+		switch key {
+		case "cpu":
+			v.validateCPU(valueNode, path+".cpu")
+		case "ephemeral-storage":
+			v.validateQuantity(valueNode, path+".ephemeral-storage")
+		default:
+			if n, err := v.parseInt(valueNode); err != nil {
+				v.addError(RuleType, valueNode, path+"."+key+" must be int")
+			} else if n < 0 {
+				v.addError(RuleNonNegative, valueNode, path+"."+key+" must not be negative")
+			}
+		case "memory":
+			quantity, ok := v.validateQuantity(valueNode, path+".memory")
+			if !ok {
+				continue
+			}
+			if quantity == 0 {
+				v.addError(RuleMemoryZero, valueNode, path+".memory must be greater than 0")
+			} else if granularity, err := ParseMemory(v.MemoryGranularity); err == nil && granularity > 0 && quantity%granularity != 0 {
+				v.addError(RuleMemoryGranularity, valueNode, path+".memory "+valueNode.Value+" is not a multiple of "+v.MemoryGranularity)
+			}
+		}
+	}
+}
+
+// extendedResource reports whether name is one of the allowed extended
+// resources.
+func (v *podValidator) extendedResource(name string) bool {
+	for _, resource := range v.ExtendedResources {
+		if name == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// validateQuantity checks that node is a byte quantity such as 128Mi and
+// returns it in bytes. ok is false if it is not.
+func (v *podValidator) validateQuantity(node *yaml.Node, field string) (quantity int64, ok bool) {
+	if node.Kind != yaml.ScalarNode {
+		v.addError(RuleType, node, field+" must be string")
+		return 0, false
+	}
+	quantity, err := ParseMemory(node.Value)
+	if errors.Is(err, errMemoryOverflow) {
+		v.addError(RuleMemoryFormat, node, field+" value out of range")
+		return 0, false
+	}
+	if err != nil {
+		v.addError(RuleMemoryFormat, node, field+" has invalid format '"+node.Value+"' (expected an integer with one of the suffixes "+memoryUnitList+")")
+		return 0, false
+	}
+	return quantity, true
+}
+
+// validateCPU accepts either a whole number of cores (2) or a number of
+// millicores (500m).
+func (v *podValidator) validateCPU(node *yaml.Node, field string) {
+	if node.Kind == yaml.ScalarNode {
+		if millicoresRegex.MatchString(node.Value) {
+			return
+		}
+		if cores, err := v.parseInt(node); err == nil && cores >= 0 {
+			return
+		}
+	}
+	v.addError(RuleCPUFormat, node, field+" must be int (cores) or string like '500m' (millicores)")
+}
+
+// validateBool checks that node is a YAML boolean and returns its value.
+// Quoted "true" is a string and 1 is an int, so neither is accepted. ok is
+// false if node is not a boolean. In strict mode, a YAML 1.1 boolean such as
+// yes is reported with a warning suggesting true or false instead.
+func (v *podValidator) validateBool(node *yaml.Node, field string) (value bool, ok bool) {
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!bool" {
+		if b, err := strconv.ParseBool(node.Value); err == nil {
+			return b, true
+		}
+	}
+	if v.Strict && node.Kind == yaml.ScalarNode && node.Tag == "!!str" && legacyBools[strings.ToLower(node.Value)] {
+		v.addWarning(RuleLegacyBool, node, field+" is the string '"+node.Value+"', not a boolean; write true or false")
+	} else {
+		v.addError(RuleType, node, field+" must be bool")
+	}
+	return false, false
+}
+
+// parseCPU converts a CPU quantity, either cores (2) or millicores (500m), to
+// millicores.
+func parseCPU(s string) (int64, error) {
+	if millicoresRegex.MatchString(s) {
+		return strconv.ParseInt(strings.TrimSuffix(s, "m"), 10, 64)
+	}
+	cores, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return cores * 1000, nil
+}
+
+// errMemoryOverflow is returned by ParseMemory for quantities that do not fit
+// in an int64 number of bytes.
+var errMemoryOverflow = errors.New("memory quantity out of range")
+
+// ParseMemory converts a memory quantity such as 128Mi to bytes.
+func ParseMemory(s string) (int64, error) {
+	m := memoryUnitRegex.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid memory quantity '%s'", s)
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, errMemoryOverflow
+	}
+	unit := memoryUnits[m[2]]
+	if n > math.MaxInt64/unit {
+		return 0, errMemoryOverflow
+	}
+	return n * unit, nil
+}
+
+func (v *podValidator) parseInt(node *yaml.Node) (int, error) {
+	if node.Kind != yaml.ScalarNode {
+		return 0, fmt.Errorf("not a scalar")
+	}
+	i, err := strconv.Atoi(node.Value)
+	if err != nil {
+		return 0, err
+	}
+	return i, nil
+}
+
+// checkDuplicateKeys reports every mapping key in the tree under node that
+// repeats an earlier key of the same mapping. YAML parsers silently keep only
+// one of the values, which usually hides a mistake.
+func (v *podValidator) checkDuplicateKeys(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		seen := make(map[string]bool)
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i]
+			if key.Kind != yaml.ScalarNode {
+				continue
+			}
+			if seen[key.Value] {
+				v.addError(RuleDuplicateKey, key, "duplicate key '"+key.Value+"'")
+			}
+			seen[key.Value] = true
+		}
+	}
+	// Aliases are not followed: their target is checked where it is defined.
+	for _, child := range node.Content {
+		v.checkDuplicateKeys(child)
+	}
+}
+
+// checkUnknownFields warns, in strict mode only, about the keys of node that
+// are not in known.
+func (v *podValidator) checkUnknownFields(node *yaml.Node, path string, known map[string]bool) {
+	v.trace(node, path)
+	if !v.Strict {
+		return
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		if key.Kind == yaml.ScalarNode && !known[key.Value] {
+			v.addWarning(RuleUnknownField, key, path+" has unknown field '"+key.Value+"'")
+		}
+	}
+}
+
+// trace logs that the validator descends into node, found at path. Every
+// object is checked for unknown fields first, so that is where it is called.
+func (v *podValidator) trace(node *yaml.Node, path string) {
+	if v.Logger == nil {
+		return
+	}
+	v.Logger.Debug("validating", "file", v.filename, "path", v.prefix+path, "kind", nodeKind(node.Kind), "line", node.Line)
+}
+
+// nodeKind returns the name of a YAML node kind.
+func nodeKind(kind yaml.Kind) string {
+	switch kind {
+	case yaml.DocumentNode:
+		return "document"
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.ScalarNode:
+		return "scalar"
+	case yaml.AliasNode:
+		return "alias"
+	}
+	return "unknown"
+}
+
+func (v *podValidator) parseMapping(node *yaml.Node) map[string]*yaml.Node {
+	result := make(map[string]*yaml.Node)
+	if node.Kind != yaml.MappingNode {
+		return result
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		key := node.Content[i]
+		value := node.Content[i+1]
+		if key.Kind == yaml.ScalarNode {
+			result[key.Value] = value
+		}
+	}
+	return result
+}
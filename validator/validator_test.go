@@ -0,0 +1,417 @@
+package validator
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readFixture returns the content of a file in testdata.
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestSyntaxErrorLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{"unclosed flow sequence", "a: 1\nb: 2\nc: 3\nd: [1, 2\ne: 5\n", 4},
+		{"unclosed flow sequence at end", "a: 1\nb: [1\n", 2},
+		{"closed multi-line sequence before", "a: [1,\n  2]\nb: 1\nc: [3\n", 4},
+		{"key at wrong indentation", "apiVersion: v1\nkind: Pod\nmetadata:\n  name: a\n y: 1\n", 5},
+		{"character that cannot start a token", "a: 1\nb: @x\n", 2},
+		{"unclosed flow sequence in a long file", "items:\n" + strings.Repeat("  - 1\n", 5000) + "spec: [1\n", 5002},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Validate([]byte(tt.content), "test.yaml", Options{})
+			if len(errs) != 1 || errs[0].Rule != RuleSyntax {
+				t.Fatalf("Validate() = %v, want a single syntax error", errs)
+			}
+			if errs[0].Line != tt.want {
+				t.Errorf("syntax error %q reported on line %d, want %d", errs[0].Message, errs[0].Line, tt.want)
+			}
+			if !strings.HasPrefix(errs[0].Message, "cannot unmarshal YAML: ") {
+				t.Errorf("message = %q, want it to start with 'cannot unmarshal YAML: '", errs[0].Message)
+			}
+		})
+	}
+}
+
+func TestTabIndentation(t *testing.T) {
+	tests := []struct {
+		fixture string
+		line    int
+		message string
+	}{
+		{"tab-indented.yaml", 9, "found tab character in indentation; YAML requires spaces"},
+		// A tab inside a block scalar is legal and must not be blamed for
+		// the unclosed bracket after it.
+		{"tab-in-block-scalar.yaml", 10, "cannot unmarshal YAML: did not find expected node content"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			errs := Validate(readFixture(t, tt.fixture), tt.fixture, Options{})
+			if len(errs) != 1 {
+				t.Fatalf("Validate() = %v, want a single error", errs)
+			}
+			if errs[0].Line != tt.line || errs[0].Message != tt.message {
+				t.Errorf("Validate() = %d %q, want %d %q", errs[0].Line, errs[0].Message, tt.line, tt.message)
+			}
+		})
+	}
+}
+
+// pod returns a valid Pod manifest with extra appended to its only
+// container, which is indented to match.
+func pod(extra string) []byte {
+	return []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+spec:
+  containers:
+    - name: app
+      image: registry.bigbrother.io/app:1.0
+      resources:
+        limits: {cpu: 1, memory: 128Mi}
+` + extra)
+}
+
+func TestPortRange(t *testing.T) {
+	tests := []struct {
+		port string
+		want string
+	}{
+		{"0", "spec.containers[0].ports[0].containerPort must be between 1 and 65535"},
+		{"-1", "spec.containers[0].ports[0].containerPort must be between 1 and 65535"},
+		{"65536", "spec.containers[0].ports[0].containerPort must be between 1 and 65535"},
+		{"1", ""},
+		{"65535", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.port, func(t *testing.T) {
+			errs := Validate(pod("      ports:\n        - containerPort: "+tt.port+"\n"), "pod.yaml", Options{})
+			if tt.want == "" {
+				if len(errs) != 0 {
+					t.Fatalf("Validate() = %v, want no errors", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Message != tt.want || errs[0].Rule != RulePortRange {
+				t.Fatalf("Validate() = %v, want %q", errs, tt.want)
+			}
+			if errs[0].Line != 12 {
+				t.Errorf("error on line %d, want 12", errs[0].Line)
+			}
+		})
+	}
+}
+
+func TestProbePortRange(t *testing.T) {
+	for _, port := range []string{"0", "-1", "65536"} {
+		t.Run(port, func(t *testing.T) {
+			probe := "      livenessProbe:\n        httpGet: {path: /healthz, port: " + port + "}\n"
+			errs := Validate(pod(probe), "pod.yaml", Options{})
+			want := "spec.containers[0].livenessProbe.httpGet.port must be between 1 and 65535"
+			if len(errs) != 1 || errs[0].Message != want {
+				t.Fatalf("Validate() = %v, want %q", errs, want)
+			}
+		})
+	}
+	errs := Validate(pod("      livenessProbe:\n        httpGet: {path: /healthz, port: 65535}\n"), "pod.yaml", Options{})
+	if len(errs) != 0 {
+		t.Errorf("Validate() = %v, want no errors for port 65535", errs)
+	}
+}
+
+func TestMultiDocument(t *testing.T) {
+	errs := Validate(readFixture(t, "multi-document.yaml"), "multi-document.yaml", Options{})
+	if len(errs) != 1 {
+		t.Fatalf("Validate() = %v, want a single error in the second document", errs)
+	}
+	e := errs[0]
+	if e.Filename != "multi-document.yaml" || e.Line != 21 || e.Rule != RuleMemoryFormat {
+		t.Errorf("Validate() = %v, want a %s error on line 21 of multi-document.yaml", e, RuleMemoryFormat)
+	}
+}
+
+func TestDuplicateKey(t *testing.T) {
+	content := []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: web
+  name: api
+spec:
+  containers:
+    - name: app
+      image: registry.bigbrother.io/app:1.0
+      resources:
+        limits: {cpu: 1, memory: 128Mi}
+`)
+	errs := Validate(content, "pod.yaml", Options{})
+	if len(errs) != 1 {
+		t.Fatalf("Validate() = %v, want a single error", errs)
+	}
+	e := errs[0]
+	if e.Rule != RuleDuplicateKey || e.Message != "duplicate key 'name'" || e.Line != 5 {
+		t.Errorf("Validate() = %v, want duplicate key 'name' on line 5", e)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	if errs := Validate(readFixture(t, "pod.json"), "pod.json", Options{}); len(errs) != 0 {
+		t.Errorf("Validate(pod.json) = %v, want no errors", errs)
+	}
+
+	// JSON strings are !!str, so a quoted boolean is still rejected.
+	errs := Validate(readFixture(t, "invalid-pod.json"), "invalid-pod.json", Options{})
+	want := "spec.containers[0].securityContext.runAsNonRoot must be bool"
+	if len(errs) != 1 || errs[0].Message != want || errs[0].Line != 11 {
+		t.Errorf("Validate(invalid-pod.json) = %v, want %q on line 11", errs, want)
+	}
+}
+
+// scalar decodes a single YAML value into a node.
+func scalar(t *testing.T, value string) *yaml.Node {
+	t.Helper()
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc.Content[0]
+}
+
+func TestValidateBool(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   bool
+		wantOK bool
+	}{
+		{"true", true, true},
+		{"false", false, true},
+		{`"true"`, false, false},
+		{"'false'", false, false},
+		{"1", false, false},
+		{"0", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			v := &podValidator{}
+			got, ok := v.validateBool(scalar(t, tt.value), "privileged")
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("validateBool(%s) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+			if tt.wantOK {
+				if len(v.errs) != 0 {
+					t.Errorf("validateBool(%s) reported %v", tt.value, v.errs)
+				}
+				return
+			}
+			if len(v.errs) != 1 || v.errs[0].Message != "privileged must be bool" {
+				t.Errorf("validateBool(%s) reported %v, want 'privileged must be bool'", tt.value, v.errs)
+			}
+		})
+	}
+}
+
+func TestValidateLegacyBool(t *testing.T) {
+	tests := []struct {
+		strict bool
+		rule   string
+		want   string
+	}{
+		{false, RuleType, "privileged must be bool"},
+		{true, RuleLegacyBool, "privileged is the string 'yes', not a boolean; write true or false"},
+	}
+	for _, tt := range tests {
+		v := &podValidator{Options: Options{Strict: tt.strict}}
+		if _, ok := v.validateBool(scalar(t, "yes"), "privileged"); ok {
+			t.Errorf("validateBool(yes) accepted the string with Strict %v", tt.strict)
+		}
+		if len(v.errs) != 1 || v.errs[0].Rule != tt.rule || v.errs[0].Message != tt.want {
+			t.Errorf("validateBool(yes) with Strict %v reported %v, want only %q", tt.strict, v.errs, tt.want)
+		}
+	}
+}
+
+func TestMetadataNameFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"web-1.example", true},
+		{"Web", false},
+		{"my_pod", false},
+		{".web", false},
+		{"web.", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := strings.Replace(string(pod("")), "name: web", "name: "+tt.name, 1)
+			errs := Validate([]byte(content), "pod.yaml", Options{})
+			if tt.valid {
+				if len(errs) != 0 {
+					t.Errorf("Validate() = %v, want no errors", errs)
+				}
+				return
+			}
+			want := "metadata.name has invalid format '" + tt.name + "'"
+			if len(errs) != 1 || errs[0].Message != want || errs[0].Rule != RuleNameFormat || errs[0].Line != 4 {
+				t.Errorf("Validate() = %v, want %q on line 4", errs, want)
+			}
+		})
+	}
+}
+
+func TestProbePath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/healthz", ""},
+		{"/api/v1/ready", ""},
+		{"'/foo bar'", "spec.containers[0].readinessProbe.httpGet.path has invalid format '/foo bar'"},
+		{"//foo", "spec.containers[0].readinessProbe.httpGet.path has invalid format '//foo'"},
+		{"/foo?x=1", "spec.containers[0].readinessProbe.httpGet.path has invalid format '/foo?x=1'"},
+		{"healthz", "spec.containers[0].readinessProbe.httpGet.path must be absolute path"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			probe := "      readinessProbe:\n        httpGet:\n          path: " + tt.path + "\n          port: 8080\n"
+			errs := Validate(pod(probe), "pod.yaml", Options{})
+			if tt.want == "" {
+				if len(errs) != 0 {
+					t.Errorf("Validate() = %v, want no errors", errs)
+				}
+				return
+			}
+			if len(errs) != 1 || errs[0].Message != tt.want || errs[0].Rule != RuleProbePath || errs[0].Line != 13 {
+				t.Errorf("Validate() = %v, want %q on line 13", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestEmptyInput(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    string
+	}{
+		{"empty.yaml", "file is empty"},
+		{"comments-only.yaml", "file contains no YAML documents"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			errs := Validate(readFixture(t, tt.fixture), tt.fixture, Options{})
+			if len(errs) != 1 || errs[0].Message != tt.want || errs[0].Rule != RuleEmptyDocument {
+				t.Errorf("Validate() = %v, want %q", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeKeys(t *testing.T) {
+	content := readFixture(t, "merge-keys.yaml")
+	if errs := Validate(content, "merge-keys.yaml", Options{}); len(errs) != 0 {
+		t.Fatalf("Validate() = %v, want the merged containers to satisfy spec.containers", errs)
+	}
+
+	// An error in the anchored spec is reported for the Pod merging it too,
+	// at the line of the anchored value.
+	invalid := strings.Replace(string(content), "memory: 128Mi", "memory: 128mb", 1)
+	errs := Validate([]byte(invalid), "merge-keys.yaml", Options{})
+	want := []string{
+		"items[0].spec.containers[0].resources.limits.memory",
+		"items[1].spec.containers[0].resources.limits.memory",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Validate() = %v, want %d errors", errs, len(want))
+	}
+	for i, e := range errs {
+		if !strings.HasPrefix(e.Message, want[i]+" has invalid format") || e.Line != 13 {
+			t.Errorf("error %d = %v, want %s on line 13", i, e, want[i])
+		}
+	}
+}
+
+func TestEnvValue(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{`"8080"`, true},
+		{"production", true},
+		{"8080", false},
+		{"true", false},
+		{"{a: b}", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			errs := Validate(pod("      env:\n        - name: PORT\n          value: "+tt.value+"\n"), "pod.yaml", Options{})
+			if tt.valid {
+				if len(errs) != 0 {
+					t.Errorf("Validate() = %v, want no errors", errs)
+				}
+				return
+			}
+			want := "spec.containers[0].env[0].value must be string"
+			if len(errs) != 1 || errs[0].Message != want || errs[0].Rule != RuleType || errs[0].Line != 13 {
+				t.Errorf("Validate() = %v, want %q on line 13", errs, want)
+			}
+		})
+	}
+}
+
+func TestStringMapValues(t *testing.T) {
+	for _, field := range []string{"labels", "annotations"} {
+		for _, value := range []string{"8080", "true"} {
+			t.Run(field+"/"+value, func(t *testing.T) {
+				content := strings.Replace(string(pod("")), "  name: web\n", "  name: web\n  "+field+":\n    tier: "+value+"\n", 1)
+				errs := Validate([]byte(content), "pod.yaml", Options{})
+				want := "metadata." + field + " keys and values must be strings"
+				if len(errs) != 1 || errs[0].Message != want || errs[0].Rule != RuleType || errs[0].Line != 6 {
+					t.Errorf("Validate() = %v, want %q on line 6", errs, want)
+				}
+			})
+		}
+		t.Run(field+"/quoted", func(t *testing.T) {
+			content := strings.Replace(string(pod("")), "  name: web\n", "  name: web\n  "+field+":\n    tier: \"8080\"\n", 1)
+			if errs := Validate([]byte(content), "pod.yaml", Options{}); len(errs) != 0 {
+				t.Errorf("Validate() = %v, want no errors", errs)
+			}
+		})
+	}
+}
+
+func TestContainerNamePattern(t *testing.T) {
+	content := strings.Replace(string(pod("")), "name: app", "name: my-app", 1)
+	tests := []struct {
+		opts Options
+		want string
+		hint string
+	}{
+		{Options{}, "spec.containers[0].name has invalid format 'my-app' (must match '^[a-z][a-z0-9_]*$')", Explain(RuleContainerName)},
+		{Options{NamePattern: regexp.MustCompile(`^[a-z]+$`)}, "spec.containers[0].name has invalid format 'my-app' (must match '^[a-z]+$')", "rename the container to match ^[a-z]+$"},
+	}
+	for _, tt := range tests {
+		errs := Validate([]byte(content), "pod.yaml", tt.opts)
+		if len(errs) != 1 || errs[0].Message != tt.want || errs[0].Rule != RuleContainerName {
+			t.Errorf("Validate() = %v, want %q", errs, tt.want)
+		}
+		if hint := tt.opts.Explain(RuleContainerName); hint != tt.hint {
+			t.Errorf("Explain() = %q, want %q", hint, tt.hint)
+		}
+	}
+}